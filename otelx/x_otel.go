@@ -0,0 +1,196 @@
+package otelx
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github/boloboom/golix/utilsx"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	INSTRUMENTATION_NAME string = "github/boloboom/golix/utilsx"
+	URL_REDACTED         string = "REDACTED" // replaces sensitive values in url.full
+)
+
+// DefaultRedactQueryParams are the query parameters whose values are redacted
+// from url.full unless WithRedactQueryParams is used. Names are case-insensitive.
+var DefaultRedactQueryParams = []string{
+	"access_token", "api_key", "apikey", "auth", "client_secret", "code", "key", "password",
+	"secret", "sig", "signature", "token", "AWSAccessKeyId", "X-Amz-Credential",
+	"X-Amz-Security-Token", "X-Amz-Signature", "X-Goog-Credential", "X-Goog-Signature",
+}
+
+// Tracer is a utilsx.Tracer recording OpenTelemetry client spans.
+//
+// Spans are children of the OpenTelemetry span of the request context.
+type Tracer struct {
+	tracer      trace.Tracer
+	redactQuery map[string]bool // lower-cased names of the redacted query parameters
+}
+
+// TracerOption configures a Tracer.
+type TracerOption func(*Tracer)
+
+type span struct {
+	span trace.Span
+}
+
+// HttpMetrics is a utilsx.HttpMetrics recording OpenTelemetry instruments.
+type HttpMetrics struct {
+	duration metric.Float64Histogram
+	errors   metric.Int64Counter
+}
+
+// NewTracer creates a new Tracer.
+//
+// The url.full attribute leaves out the user info of the URL and redacts the
+// values of the DefaultRedactQueryParams query parameters.
+//
+// Parameters:
+//   - provider: the tracer provider, otel.GetTracerProvider() when nil.
+//   - opts: the tracer options.
+//
+// Returns a pointer to the created Tracer.
+func NewTracer(provider trace.TracerProvider, opts ...TracerOption) *Tracer {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	t := &Tracer{tracer: provider.Tracer(INSTRUMENTATION_NAME)}
+	WithRedactQueryParams(DefaultRedactQueryParams...)(t)
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// WithRedactQueryParams redacts the values of the given query parameters from
+// url.full instead of DefaultRedactQueryParams. No name disables the redaction.
+func WithRedactQueryParams(names ...string) TracerOption {
+	return func(t *Tracer) {
+		t.redactQuery = make(map[string]bool, len(names))
+		for _, name := range names {
+			t.redactQuery[strings.ToLower(name)] = true
+		}
+	}
+}
+
+// Start implements utilsx.Tracer.
+func (t *Tracer) Start(ctx context.Context, req *http.Request) (context.Context, utilsx.Span) {
+	attrs := []attribute.KeyValue{
+		attribute.String("http.request.method", req.Method),
+		attribute.String("server.address", req.URL.Hostname()),
+		attribute.String("url.full", t.redactedUrl(req)),
+	}
+	if port, err := strconv.Atoi(req.URL.Port()); err == nil {
+		attrs = append(attrs, attribute.Int("server.port", port))
+	}
+	ctx, s := t.tracer.Start(ctx, req.Method, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	return ctx, &span{span: s}
+}
+
+// SpanContext implements utilsx.Span.
+func (s *span) SpanContext() utilsx.SpanContext {
+	spanContext := s.span.SpanContext()
+	return utilsx.SpanContext{
+		TraceID:    spanContext.TraceID(),
+		SpanID:     spanContext.SpanID(),
+		Flags:      byte(spanContext.TraceFlags()),
+		TraceState: spanContext.TraceState().String(),
+	}
+}
+
+// End implements utilsx.Span.
+func (s *span) End(statusCode int, err error) {
+	switch {
+	case err != nil:
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	case statusCode >= http.StatusBadRequest:
+		s.span.SetStatus(codes.Error, "")
+	}
+	if statusCode > 0 {
+		s.span.SetAttributes(attribute.Int("http.response.status_code", statusCode))
+	}
+	s.span.End()
+}
+
+// NewHttpMetrics creates a new HttpMetrics.
+//
+// It records the "http.client.request.duration" histogram, in seconds, and
+// the "http.client.request.errors" counter.
+//
+// Parameters:
+//   - provider: the meter provider, otel.GetMeterProvider() when nil.
+//
+// Returns:
+//   - *HttpMetrics: the created metrics.
+//   - error: the instrument creation error.
+func NewHttpMetrics(provider metric.MeterProvider) (*HttpMetrics, error) {
+	if provider == nil {
+		provider = otel.GetMeterProvider()
+	}
+	meter := provider.Meter(INSTRUMENTATION_NAME)
+	duration, err := meter.Float64Histogram("http.client.request.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of HTTP client requests until the response headers."),
+		metric.WithExplicitBucketBoundaries(0.005, 0.01, 0.025, 0.05, 0.075, 0.1, 0.25, 0.5, 0.75, 1, 2.5, 5, 7.5, 10))
+	if err != nil {
+		return nil, err
+	}
+	counter, err := meter.Int64Counter("http.client.request.errors",
+		metric.WithDescription("Number of failed HTTP client requests."))
+	if err != nil {
+		return nil, err
+	}
+	return &HttpMetrics{duration: duration, errors: counter}, nil
+}
+
+// ObserveDuration implements utilsx.HttpMetrics.
+func (m *HttpMetrics) ObserveDuration(host, method string, statusCode int, duration time.Duration) {
+	attrs := []attribute.KeyValue{
+		attribute.String("server.address", host),
+		attribute.String("http.request.method", method),
+	}
+	if statusCode > 0 {
+		attrs = append(attrs, attribute.Int("http.response.status_code", statusCode))
+	}
+	m.duration.Record(context.Background(), duration.Seconds(), metric.WithAttributes(attrs...))
+}
+
+// CountError implements utilsx.HttpMetrics.
+func (m *HttpMetrics) CountError(host, method, reason string) {
+	m.errors.Add(context.Background(), 1, metric.WithAttributes(
+		attribute.String("server.address", host),
+		attribute.String("http.request.method", method),
+		attribute.String("error.type", reason),
+	))
+}
+
+// redactedUrl returns the request URL without its user info and with the
+// values of the sensitive query parameters redacted.
+func (t *Tracer) redactedUrl(req *http.Request) string {
+	u := *req.URL
+	u.User = nil
+	if u.RawQuery != "" && len(t.redactQuery) > 0 {
+		// the query is rewritten in place to keep the order and encoding of the other parameters
+		params := strings.Split(u.RawQuery, "&")
+		for i, param := range params {
+			name, _, _ := strings.Cut(param, "=")
+			if unescaped, err := url.QueryUnescape(name); err == nil && t.redactQuery[strings.ToLower(unescaped)] {
+				params[i] = name + "=" + URL_REDACTED
+			}
+		}
+		u.RawQuery = strings.Join(params, "&")
+	}
+	return u.String()
+}
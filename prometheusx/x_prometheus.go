@@ -0,0 +1,61 @@
+package prometheusx
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// HttpMetrics is a utilsx.HttpMetrics recording Prometheus collectors.
+type HttpMetrics struct {
+	duration *prometheus.HistogramVec
+	errors   *prometheus.CounterVec
+}
+
+// NewHttpMetrics creates a new HttpMetrics and registers its collectors.
+//
+// It records the "http_client_request_duration_seconds" histogram, labeled by
+// host, method and status, and the "http_client_request_errors_total"
+// counter, labeled by host, method and reason.
+//
+// Parameters:
+//   - registerer: the registerer, prometheus.DefaultRegisterer when nil.
+//
+// Returns:
+//   - *HttpMetrics: the created metrics.
+//   - error: the registration error.
+func NewHttpMetrics(registerer prometheus.Registerer) (*HttpMetrics, error) {
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+	metrics := &HttpMetrics{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_client_request_duration_seconds",
+			Help:    "Duration of HTTP client requests until the response headers.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"host", "method", "status"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_client_request_errors_total",
+			Help: "Number of failed HTTP client requests.",
+		}, []string{"host", "method", "reason"}),
+	}
+	if err := registerer.Register(metrics.duration); err != nil {
+		return nil, err
+	}
+	if err := registerer.Register(metrics.errors); err != nil {
+		registerer.Unregister(metrics.duration)
+		return nil, err
+	}
+	return metrics, nil
+}
+
+// ObserveDuration implements utilsx.HttpMetrics.
+func (m *HttpMetrics) ObserveDuration(host, method string, statusCode int, duration time.Duration) {
+	m.duration.WithLabelValues(host, method, strconv.Itoa(statusCode)).Observe(duration.Seconds())
+}
+
+// CountError implements utilsx.HttpMetrics.
+func (m *HttpMetrics) CountError(host, method, reason string) {
+	m.errors.WithLabelValues(host, method, reason).Inc()
+}
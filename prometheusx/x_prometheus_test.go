@@ -0,0 +1,47 @@
+package prometheusx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github/boloboom/golix/utilsx"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestHttpMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	registry := prometheus.NewRegistry()
+	metrics, err := NewHttpMetrics(registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := utilsx.NewHttpClient(utilsx.WithMiddleware(utilsx.MetricsMiddleware(metrics)))
+	client.NewRequest(server.URL).Do(utilsx.HTTP_METHOD_GET)
+	client.NewRequest(server.URL).SetUri("broken").Do(utilsx.HTTP_METHOD_GET)
+	client.NewRequest(server.URL).SetUri("broken").Do(utilsx.HTTP_METHOD_GET)
+
+	// 按主机、方法和状态码记录耗时
+	if count := testutil.CollectAndCount(metrics.duration); count != 2 {
+		t.Fatalf("expected 2 duration series, got %d", count)
+	}
+	// 按原因统计错误
+	if value := testutil.ToFloat64(metrics.errors.WithLabelValues(host, "GET", utilsx.METRIC_ERROR_SERVER)); value != 2 {
+		t.Fatalf("expected 2 server errors, got %v", value)
+	}
+
+	// 重复注册返回错误
+	if _, err = NewHttpMetrics(registry); err == nil {
+		t.Fatal("expected duplicate registration error")
+	}
+}
@@ -0,0 +1,72 @@
+package redisx
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// HttpCacheStore stores cached HTTP responses in redis, so that every replica
+// shares the same cache.
+//
+// It implements utilsx.CacheStore.
+type HttpCacheStore struct {
+	client *redis.Client
+}
+
+// NewHttpCacheStore creates a new HttpCacheStore using the client set up by Setup.
+//
+// No parameters.
+// Returns a pointer to the created HttpCacheStore.
+func NewHttpCacheStore() *HttpCacheStore {
+	return &HttpCacheStore{client: Cache().Client}
+}
+
+// Get returns the cached response stored at key.
+//
+// Parameters:
+//   - ctx: the context of the redis call.
+//   - key: the cache key, prefixed with the cache prefix.
+//
+// Returns:
+//   - []byte: the cached response.
+//   - bool: false when nothing is cached at key.
+//   - error: the redis error.
+func (s *HttpCacheStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := s.client.Get(ctx, CacheKey("http_cache:"+key).Key()).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+// Set stores a cached response at key for ttl.
+//
+// Parameters:
+//   - ctx: the context of the redis call.
+//   - key: the cache key, prefixed with the cache prefix.
+//   - value: the cached response.
+//   - ttl: how long the response is kept.
+//
+// Returns:
+//   - error: the redis error.
+func (s *HttpCacheStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return s.client.Set(ctx, CacheKey("http_cache:"+key).Key(), value, ttl).Err()
+}
+
+// Delete removes the cached response stored at key.
+//
+// Parameters:
+//   - ctx: the context of the redis call.
+//   - key: the cache key, prefixed with the cache prefix.
+//
+// Returns:
+//   - error: the redis error.
+func (s *HttpCacheStore) Delete(ctx context.Context, key string) error {
+	return s.client.Del(ctx, CacheKey("http_cache:"+key).Key()).Err()
+}
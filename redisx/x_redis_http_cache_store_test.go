@@ -0,0 +1,26 @@
+package redisx
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestHttpCacheStore(t *testing.T) {
+	Setup(testSetting)
+	store := NewHttpCacheStore()
+	ctx := context.Background()
+	key := "test_http_cache"
+	store.Delete(ctx, key)
+
+	if _, ok, err := store.Get(ctx, key); err != nil || ok {
+		t.Fatalf("expected cache miss, got %v %v", ok, err)
+	}
+	if err := store.Set(ctx, key, []byte("cached"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	value, ok, err := store.Get(ctx, key)
+	if err != nil || !ok || string(value) != "cached" {
+		t.Fatalf("unexpected cache entry %q %v %v", value, ok, err)
+	}
+}
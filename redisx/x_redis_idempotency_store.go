@@ -0,0 +1,81 @@
+package redisx
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// IdempotencyStore stores the responses of idempotent requests in redis, so
+// that a duplicate is replayed whichever replica receives it.
+//
+// It implements utilsx.IdempotencyStore.
+type IdempotencyStore struct {
+	client *redis.Client
+}
+
+// NewIdempotencyStore creates a new IdempotencyStore using the client set up by Setup.
+//
+// No parameters.
+// Returns a pointer to the created IdempotencyStore.
+func NewIdempotencyStore() *IdempotencyStore {
+	return &IdempotencyStore{client: Cache().Client}
+}
+
+// Reserve claims key for ttl while its first request is handled.
+//
+// A reserved key holds an empty value until Complete stores the response.
+//
+// Parameters:
+//   - ctx: the context of the redis call.
+//   - key: the idempotency key.
+//   - ttl: how long the key stays reserved.
+//
+// Returns:
+//   - bool: true when the key was free.
+//   - []byte: the stored response, nil while the first request is in progress.
+//   - error: the redis error.
+func (s *IdempotencyStore) Reserve(ctx context.Context, key string, ttl time.Duration) (bool, []byte, error) {
+	redisKey := CacheKey("idempotency:" + key).Key()
+	reserved, err := s.client.SetNX(ctx, redisKey, "", ttl).Result()
+	if err != nil || reserved {
+		return reserved, nil, err
+	}
+	value, err := s.client.Get(ctx, redisKey).Bytes()
+	if errors.Is(err, redis.Nil) || len(value) == 0 {
+		// expired in between or still in progress
+		return false, nil, nil
+	}
+	if err != nil {
+		return false, nil, err
+	}
+	return false, value, nil
+}
+
+// Complete stores the response of key for ttl.
+//
+// Parameters:
+//   - ctx: the context of the redis call.
+//   - key: the idempotency key.
+//   - response: the encoded response.
+//   - ttl: how long the response is replayed.
+//
+// Returns:
+//   - error: the redis error.
+func (s *IdempotencyStore) Complete(ctx context.Context, key string, response []byte, ttl time.Duration) error {
+	return s.client.Set(ctx, CacheKey("idempotency:"+key).Key(), response, ttl).Err()
+}
+
+// Release frees key.
+//
+// Parameters:
+//   - ctx: the context of the redis call.
+//   - key: the idempotency key.
+//
+// Returns:
+//   - error: the redis error.
+func (s *IdempotencyStore) Release(ctx context.Context, key string) error {
+	return s.client.Del(ctx, CacheKey("idempotency:"+key).Key()).Err()
+}
@@ -0,0 +1,35 @@
+package redisx
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyStore(t *testing.T) {
+	Setup(testSetting)
+	store := NewIdempotencyStore()
+	ctx := context.Background()
+	key := "test_idempotency_key"
+	store.Release(ctx, key)
+
+	reserved, _, err := store.Reserve(ctx, key, time.Minute)
+	if err != nil || !reserved {
+		t.Fatalf("expected reserved key, got %v %v", reserved, err)
+	}
+	// 处理中的请求没有响应
+	reserved, response, err := store.Reserve(ctx, key, time.Minute)
+	if err != nil || reserved || response != nil {
+		t.Fatalf("expected key in progress, got %v %q %v", reserved, response, err)
+	}
+	if err = store.Complete(ctx, key, []byte("response"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	reserved, response, err = store.Reserve(ctx, key, time.Minute)
+	if err != nil || reserved || string(response) != "response" {
+		t.Fatalf("expected stored response, got %v %q %v", reserved, response, err)
+	}
+	if err = store.Release(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,38 @@
+package redisx
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// NonceStore remembers request nonces in redis, so that a replayed request is
+// rejected whichever replica receives it.
+//
+// It implements utilsx.NonceStore.
+type NonceStore struct {
+	client *redis.Client
+}
+
+// NewNonceStore creates a new NonceStore using the client set up by Setup.
+//
+// No parameters.
+// Returns a pointer to the created NonceStore.
+func NewNonceStore() *NonceStore {
+	return &NonceStore{client: Cache().Client}
+}
+
+// Remember stores nonce for ttl.
+//
+// Parameters:
+//   - ctx: the context of the redis call.
+//   - nonce: the nonce to remember.
+//   - ttl: how long the nonce is remembered.
+//
+// Returns:
+//   - bool: true when the nonce was not already stored.
+//   - error: the redis error.
+func (s *NonceStore) Remember(ctx context.Context, nonce string, ttl time.Duration) (bool, error) {
+	return s.client.SetNX(ctx, CacheKey("nonce:"+nonce).Key(), 1, ttl).Result()
+}
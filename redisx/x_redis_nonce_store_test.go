@@ -0,0 +1,23 @@
+package redisx
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNonceStore(t *testing.T) {
+	Setup(testSetting)
+	store := NewNonceStore()
+	nonce := "test_nonce"
+	Cache().Client.Del(context.Background(), CacheKey("nonce:"+nonce).Key())
+
+	fresh, err := store.Remember(context.Background(), nonce, time.Minute)
+	if err != nil || !fresh {
+		t.Fatalf("expected fresh nonce, got %v %v", fresh, err)
+	}
+	fresh, err = store.Remember(context.Background(), nonce, time.Minute)
+	if err != nil || fresh {
+		t.Fatalf("expected replayed nonce, got %v %v", fresh, err)
+	}
+}
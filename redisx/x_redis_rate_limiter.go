@@ -0,0 +1,103 @@
+package redisx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenBucketScript takes a token from the bucket stored at KEYS[1].
+//
+// ARGV[1] is the refill rate in tokens per second and ARGV[2] the bucket
+// capacity. It returns 0 when a token was taken, otherwise the number of
+// milliseconds to wait for the next token. The redis server clock is used so
+// that all replicas agree on time.
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+	tokens = burst
+	ts = now
+end
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate / 1000)
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+else
+	wait = math.ceil((1 - tokens) * 1000 / rate)
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst * 1000 / rate) + 1000)
+return wait
+`)
+
+// ErrInvalidRateLimit is returned when a RateLimiter is created with a rate
+// that is not positive or a burst below 1.
+var ErrInvalidRateLimit = errors.New("redisx: rate limit needs a positive rate and a burst of at least 1")
+
+// RateLimiter is a token bucket rate limiter whose buckets live in redis, so
+// that every replica shares the same quota.
+//
+// It implements utilsx.RateLimiter.
+type RateLimiter struct {
+	client *redis.Client
+	rate   float64
+	burst  int
+}
+
+// NewRateLimiter creates a new RateLimiter using the client set up by Setup.
+//
+// Parameters:
+//   - rate: the number of requests allowed per second, must be positive.
+//   - burst: the number of requests allowed at once, at least 1.
+//
+// Returns:
+//   - *RateLimiter: the created rate limiter.
+//   - error: ErrInvalidRateLimit for an invalid rate or burst.
+func NewRateLimiter(rate float64, burst int) (*RateLimiter, error) {
+	// the token bucket script divides by the rate
+	if !(rate > 0) || burst < 1 {
+		return nil, fmt.Errorf("%w: rate %v, burst %d", ErrInvalidRateLimit, rate, burst)
+	}
+	return &RateLimiter{
+		client: Cache().Client,
+		rate:   rate,
+		burst:  burst,
+	}, nil
+}
+
+// Wait blocks until a token of the bucket identified by key is taken or ctx is done.
+//
+// Parameters:
+//   - ctx: the context bounding the wait.
+//   - key: the quota key, prefixed with the cache prefix.
+//
+// Returns:
+//   - error: the redis error or the context error.
+func (l *RateLimiter) Wait(ctx context.Context, key string) error {
+	cacheKey := CacheKey("rate_limit:" + key).Key()
+	for {
+		wait, err := tokenBucketScript.Run(ctx, l.client, []string{cacheKey}, l.rate, l.burst).Int64()
+		if err != nil {
+			return err
+		}
+		if wait <= 0 {
+			return nil
+		}
+		timer := time.NewTimer(time.Duration(wait) * time.Millisecond)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package redisx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	Setup(testSetting)
+	limiter, err := NewRateLimiter(20, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := "test_rate_limiter"
+	Cache().Client.Del(context.Background(), CacheKey("rate_limit:"+key).Key())
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := limiter.Wait(context.Background(), key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Fatalf("requests were not limited, took %s", elapsed)
+	}
+
+	// 速率为0时Lua脚本会除以0，创建时直接返回错误
+	if _, err = NewRateLimiter(0, 1); !errors.Is(err, ErrInvalidRateLimit) {
+		t.Fatalf("expected ErrInvalidRateLimit, got %v", err)
+	}
+}
@@ -1,15 +1,13 @@
 package utilsx
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"net/url"
-	"path"
 	"time"
 )
 
@@ -25,18 +23,48 @@ const (
 	HTTP_METHOD_OPTIONS HttpMethod = "OPTIONS"
 )
 
+var (
+	// ErrRequestCanceled is returned when the request context is canceled
+	// before the response has been fully received.
+	ErrRequestCanceled = errors.New("utilsx: request canceled")
+	// ErrRequestTimeout is returned when the request deadline, either from the
+	// caller context or from SetTimeout, is exceeded.
+	ErrRequestTimeout = errors.New("utilsx: request deadline exceeded")
+)
+
 type ApiRequest struct {
+	client *HttpClient // client sending the request
+
 	method         string // request method
 	schema         string // request schema, default use https
 	serviceAddress string // request url or reuqest host
 	uri            string // request path
 
-	headers map[string]string // request headers
+	headers http.Header // request headers
+
+	query    url.Values             // request query parameters
+	queryErr error                  // error of the last SetQueryStruct call
+	pathErr  error                  // error of the path given to ServiceClient.NewRequest
+	body     map[string]interface{} // request post body
 
-	query url.Values             // request query parameters
-	body  map[string]interface{} // request post body
+	bodyMode    bodyMode        // how the request body is encoded
+	form        url.Values      // request form or multipart values
+	files       []multipartFile // request multipart files
+	rawBody     []byte          // request raw body
+	bodyReader  io.Reader       // request streamed body
+	bodyValue   interface{}     // request value marshaled as JSON or XML
+	contentType string          // content type of the raw or streamed body
+	forceBody   bool            // send the body for GET and HEAD requests
 
-	timeout time.Duration // request timeout
+	timeout         time.Duration // request timeout
+	retryPolicy     RetryPolicy   // request retry policy, nil means a single attempt
+	attempts        int           // attempts made by the last Do call
+	maxResponseSize int64         // response body size limit, 0 means no limit
+	progress        ProgressFunc  // response body download progress callback
+	middlewares     []Middleware  // request middlewares, run after the client middlewares
+	auth            AuthProvider  // request authentication, applied after all middlewares
+	compression     string        // request body content encoding, empty for an uncompressed body
+	idempotencyKey  string        // idempotency key sent by every attempt
 
 	apiResponse           *http.Response // request response
 	apiResponseStatus     string         // request response status
@@ -45,11 +73,19 @@ type ApiRequest struct {
 	apiResponseError      error          // request response error
 }
 
-// NewHttpRequest creates a new HTTP request.
+// NewHttpRequest creates a new HTTP request sent through the DefaultHttpClient.
 //
 // It takes a string parameter `address` which represents the URL of the request.
 // The function returns an `executableApiRequest` object.
 func NewHttpRequest(address string) ExecutableApiRequest {
+	return newApiRequest(DefaultHttpClient(), address)
+}
+
+// newApiRequest creates a new ApiRequest bound to the given client.
+//
+// It takes a pointer to the HttpClient and the URL of the request.
+// It returns a pointer to the created ApiRequest.
+func newApiRequest(client *HttpClient, address string) *ApiRequest {
 	Url, _ := url.Parse(address)
 	rawQuery, _ := url.ParseQuery(Url.RawQuery)
 	// default request scheme is https
@@ -57,81 +93,187 @@ func NewHttpRequest(address string) ExecutableApiRequest {
 		Url.Scheme = "https"
 	}
 	return &ApiRequest{
+		client:         client,
 		schema:         Url.Scheme,
 		serviceAddress: address,
 		query:          rawQuery,
 		body:           make(map[string]interface{}),
-		headers:        make(map[string]string),
-		timeout:        time.Second * 5,
+		form:           make(url.Values),
+		headers:        make(http.Header),
+		timeout:        client.timeout,
+		compression:    client.compression,
 	}
 }
 
 // Do sends an API request and returns the response.
 //
 // It takes a method of type httpMethod as a parameter and returns an apiResponse
-// and an error.
+// and an error. It is a shorthand for DoContext with context.Background().
 func (r *ApiRequest) Do(method HttpMethod) (apiResponse, error) {
-	defer r.printLog()
-	postBody, _ := json.Marshal(r.body)
-	req, err := r.newRequest(method, bytes.NewReader(postBody))
+	return r.DoContext(context.Background(), method)
+}
+
+// DoContext sends an API request bound to the given context and returns the response.
+//
+// The request is aborted as soon as ctx is done. The timeout set by SetTimeout
+// is applied on top of ctx, so whichever deadline comes first wins.
+//
+// Parameters:
+//   - ctx: the context controlling cancellation and deadline of the request.
+//   - method: the HTTP method of the request.
+//
+// Returns:
+//   - apiResponse: the response of the request, also returned along with an
+//     *HttpError when the response status code is 400 or above.
+//   - error: ErrRequestCanceled or ErrRequestTimeout wrapped around the
+//     transport error when ctx ends early, an *HttpError for failed
+//     responses, otherwise the transport error.
+func (r *ApiRequest) DoContext(ctx context.Context, method HttpMethod) (apiResponse, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := r.send(ctx, method, nil)
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
+
+	r.apiResponseData, err = io.ReadAll(r.limitBody(resp, 0))
+	if err != nil {
+		log.Printf("response result read error: %s", err.Error())
+		r.apiResponseError = contextError(ctx, err)
+		return nil, r.apiResponseError
+	}
+	// failed responses are still returned so that Result and Success keep working
+	if httpErr := r.newHttpError(); httpErr != nil {
+		r.apiResponseError = httpErr
+		return r, httpErr
+	}
 
-	httpClient := http.DefaultClient
-	httpClient.Timeout = r.timeout
+	return r, nil
+}
+
+// withTimeout derives the context of a Do call from ctx and the request timeout.
+//
+// It returns the derived context and the function releasing it.
+func (r *ApiRequest) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if r.timeout > 0 {
+		return context.WithTimeout(ctx, r.timeout)
+	}
+	return context.WithCancel(ctx)
+}
 
-	r.apiResponse, err = httpClient.Do(req)
+// send sends the request, retrying it according to the retry policy, and
+// returns the response with its body left unread.
+//
+// It takes the context of the call, the method of the request and extra
+// headers for this call only, which may be nil.
+// It returns the response and an error.
+func (r *ApiRequest) send(ctx context.Context, method HttpMethod, header http.Header) (*http.Response, error) {
+	r.resetResponse()
+	r.method = string(method)
+	ctx, header = r.withIdempotencyKey(ctx, method, header)
+
+	// the body is encoded once so that every attempt sends the same bytes
+	body, err := r.encodeBody(method)
 	if err != nil {
 		return nil, err
 	}
-	r.apiResponseStatus = r.apiResponse.Status
-	r.apiResponseStatusCode = r.apiResponse.StatusCode
-	r.apiResponseData, err = io.ReadAll(r.apiResponse.Body)
-	if err != nil {
-		log.Printf("response result read error: %s", err.Error())
+	if body, err = body.compress(r.compression); err != nil {
 		return nil, err
 	}
-	defer r.apiResponse.Body.Close()
+	roundTrip := r.roundTripper()
+	for r.attempts = 1; ; r.attempts++ {
+		var req *http.Request
+		req, err = r.newRequest(ctx, method, body)
+		if err != nil {
+			return nil, err
+		}
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		r.apiResponse, err = roundTrip(req)
+		if r.retryPolicy == nil || !body.replayable() {
+			break
+		}
+		delay, retry := r.retryPolicy.Retry(r.attempts, req, r.apiResponse, err)
+		if !retry {
+			break
+		}
+		if r.apiResponse != nil {
+			drainBody(r.apiResponse.Body)
+			r.apiResponse = nil
+		}
+		if err = sleepContext(ctx, delay); err != nil {
+			break
+		}
+	}
+	if err != nil {
+		r.apiResponseError = r.attemptsError(contextError(ctx, err))
+		return nil, r.apiResponseError
+	}
 
-	return r, err
+	r.apiResponseStatus = r.apiResponse.Status
+	r.apiResponseStatusCode = r.apiResponse.StatusCode
+	return r.apiResponse, nil
 }
 
-func (r *ApiRequest) printLog() {
-	jsonBody, _ := json.Marshal(r.body)
-	jsonHeaders, _ := json.Marshal(r.headers)
-	var logOut = fmt.Sprintf("%s %s Body: %s Headers: %s \n",
-		r.method,
-		r.GetUrl(),
-		string(jsonBody),
-		string(jsonHeaders),
-	)
-	if r.apiResponseStatusCode >= http.StatusOK && r.apiResponseStatusCode < http.StatusBadRequest {
-		logOut += "Success ResponseStatus: %s ResponseData: %s"
-		log.Printf(logOut, r.apiResponseStatus, string(r.apiResponseData))
-		return
+// resetResponse clears the response state left over from a previous Do call.
+func (r *ApiRequest) resetResponse() {
+	r.apiResponse = nil
+	r.apiResponseStatus = ""
+	r.apiResponseStatusCode = 0
+	r.apiResponseData = nil
+	r.apiResponseError = nil
+	r.attempts = 0
+}
+
+// attemptsError wraps err in a RetryError when more than one attempt was made.
+func (r *ApiRequest) attemptsError(err error) error {
+	if r.attempts > 1 {
+		return &RetryError{Attempts: r.attempts, Err: err}
 	}
-	if r.apiResponseStatusCode >= http.StatusBadRequest && r.apiResponseError != nil {
-		logOut += "Failed ResponseStatus: %s ResponseData: %s ResponseError: %s"
-		log.Printf(logOut, r.apiResponseStatus, string(r.apiResponseData), r.apiResponseError.Error())
-		return
+	return err
+}
+
+// contextError annotates err with ErrRequestCanceled or ErrRequestTimeout
+// when it was caused by ctx ending.
+//
+// The returned error still matches context.Canceled or
+// context.DeadlineExceeded with errors.Is.
+func contextError(ctx context.Context, err error) error {
+	switch ctxErr := ctx.Err(); {
+	case errors.Is(ctxErr, context.DeadlineExceeded):
+		return fmt.Errorf("%w: %w", ErrRequestTimeout, err)
+	case errors.Is(ctxErr, context.Canceled):
+		return fmt.Errorf("%w: %w", ErrRequestCanceled, err)
 	}
-	log.Println(logOut)
+	return err
 }
 
 // newRequest creates a new HTTP request with the given method and body.
 //
-// It takes in a method of type httpMethod and a body of type io.Reader.
+// It takes in a context, a method of type httpMethod and the encoded body,
+// which may be nil. It does not modify the apiRequest.
 // It returns a pointer to an http.Request and an error.
-func (r *ApiRequest) newRequest(method HttpMethod, body io.Reader) (*http.Request, error) {
-	r.method = string(method)
-	req, err := http.NewRequest(r.method, r.GetUrl(), body)
+func (r *ApiRequest) newRequest(ctx context.Context, method HttpMethod, body *requestBody) (*http.Request, error) {
+	if r.pathErr != nil {
+		return nil, r.pathErr
+	}
+	if r.queryErr != nil {
+		return nil, r.queryErr
+	}
+	req, err := http.NewRequestWithContext(ctx, string(method), r.GetUrl(), body.reader())
 	if err != nil {
 		return nil, err
 	}
-	for key, value := range r.headers {
-		req.Header.Set(key, value)
+	for key, values := range r.headers {
+		req.Header[key] = append([]string(nil), values...)
 	}
+	body.setHeaders(req.Header)
 	return req, nil
 }
 
@@ -143,9 +285,7 @@ func (r *ApiRequest) GetUrl() string {
 	// build url path
 	Url, _ := url.Parse(r.serviceAddress)
 	Url.Scheme = r.schema
-	if len(r.uri) > 0 {
-		Url.Path = path.Join(Url.Path, r.uri)
-	}
+	joinUrlPath(Url, &url.URL{Path: r.uri})
 	// query parameters encode
 	Url.RawQuery = r.query.Encode()
 	return Url.String()
@@ -154,10 +294,34 @@ func (r *ApiRequest) GetUrl() string {
 type ExecutableApiRequest interface {
 	SetUri(uri string) ExecutableApiRequest
 	SetHeader(key, value string) ExecutableApiRequest
+	AddHeader(key, value string) ExecutableApiRequest
+	DelHeader(key string) ExecutableApiRequest
 	SetBody(key string, value interface{}) ExecutableApiRequest
 	SetQueryParam(key string, value string) ExecutableApiRequest
+	AddQueryParam(key, value string) ExecutableApiRequest
+	DelQueryParam(key string) ExecutableApiRequest
+	SetQueryStruct(value interface{}) ExecutableApiRequest
 	SetTimeout(time.Duration) ExecutableApiRequest
+	SetRetryPolicy(policy RetryPolicy) ExecutableApiRequest
+	SetFormValue(key, value string) ExecutableApiRequest
+	SetMultipartFile(field, filename string, reader io.Reader) ExecutableApiRequest
+	SetRawBody(data []byte, contentType string) ExecutableApiRequest
+	SetBodyReader(reader io.Reader, contentType string) ExecutableApiRequest
+	SetJSONBody(value interface{}) ExecutableApiRequest
+	SetXMLBody(value interface{}) ExecutableApiRequest
+	SetForceBody(force bool) ExecutableApiRequest
+	SetMaxResponseSize(size int64) ExecutableApiRequest
+	SetProgress(progress ProgressFunc) ExecutableApiRequest
+	Use(middlewares ...Middleware) ExecutableApiRequest
+	SetAuth(provider AuthProvider) ExecutableApiRequest
+	SetCompression(encoding string) ExecutableApiRequest
+	SetIdempotencyKey(key string) ExecutableApiRequest
+	Curl(method HttpMethod, opts ...CurlOption) (string, error)
 	Do(method HttpMethod) (apiResponse, error)
+	DoContext(ctx context.Context, method HttpMethod) (apiResponse, error)
+	DoStream(ctx context.Context, method HttpMethod) (*StreamResponse, error)
+	Download(ctx context.Context, method HttpMethod, writer io.Writer) (int64, error)
+	DownloadFile(ctx context.Context, method HttpMethod, filePath string) (int64, error)
 }
 
 // SetUri sets the URI for the apiRequest.
@@ -173,7 +337,7 @@ func (r *ApiRequest) SetUri(uri string) ExecutableApiRequest {
 	return r
 }
 
-// SetHeader sets a header in the apiRequest.
+// SetHeader sets a header in the apiRequest, replacing its previous values.
 //
 // Parameters:
 //
@@ -184,12 +348,40 @@ func (r *ApiRequest) SetUri(uri string) ExecutableApiRequest {
 // Returns:
 //   - executableApiRequest: The modified apiRequest struct.
 func (r *ApiRequest) SetHeader(key, value string) ExecutableApiRequest {
-	r.headers[key] = value
+	r.headers.Set(key, value)
+	return r
+}
+
+// AddHeader adds a value to a header of the apiRequest, keeping its previous values.
+//
+// Parameters:
+//   - key: The key of the header.
+//   - value: The value to add.
+//
+// Returns:
+//   - executableApiRequest: The modified apiRequest struct.
+func (r *ApiRequest) AddHeader(key, value string) ExecutableApiRequest {
+	r.headers.Add(key, value)
+	return r
+}
+
+// DelHeader removes all the values of a header of the apiRequest.
+//
+// Parameters:
+//   - key: The key of the header.
+//
+// Returns:
+//   - executableApiRequest: The modified apiRequest struct.
+func (r *ApiRequest) DelHeader(key string) ExecutableApiRequest {
+	r.headers.Del(key)
 	return r
 }
 
 // SetBody sets a key-value pair in the postBody field of the apiRequest struct.
 //
+// The pairs are sent as a JSON object, replacing the body set by the other
+// body setters, such as SetFormValue or SetJSONBody, like they replace it.
+//
 // Parameters:
 //   - key: The key to set in the postBody map.
 //   - value: The value to set for the given key in the postBody map.
@@ -197,11 +389,12 @@ func (r *ApiRequest) SetHeader(key, value string) ExecutableApiRequest {
 // Returns:
 //   - executableApiRequest: The modified apiRequest struct.
 func (r *ApiRequest) SetBody(key string, value interface{}) ExecutableApiRequest {
+	r.bodyMode = bodyModeJSONMap
 	r.body[key] = value
 	return r
 }
 
-// SetQueryParam sets a query parameter in the apiRequest.
+// SetQueryParam sets a query parameter in the apiRequest, replacing its previous values.
 //
 // Parameters:
 //   - key: the key of the query parameter.
@@ -214,6 +407,32 @@ func (r *ApiRequest) SetQueryParam(key, value string) ExecutableApiRequest {
 	return r
 }
 
+// AddQueryParam adds a value to a query parameter of the apiRequest, so that
+// repeated keys such as ids=1&ids=2 can be sent.
+//
+// Parameters:
+//   - key: the key of the query parameter.
+//   - value: the value to add.
+//
+// Returns:
+//   - executableApiRequest: The modified apiRequest struct.
+func (r *ApiRequest) AddQueryParam(key, value string) ExecutableApiRequest {
+	r.query.Add(key, value)
+	return r
+}
+
+// DelQueryParam removes all the values of a query parameter of the apiRequest.
+//
+// Parameters:
+//   - key: the key of the query parameter.
+//
+// Returns:
+//   - executableApiRequest: The modified apiRequest struct.
+func (r *ApiRequest) DelQueryParam(key string) ExecutableApiRequest {
+	r.query.Del(key)
+	return r
+}
+
 // SetTimeout sets the timeout duration for the API request.
 //
 // Parameters:
@@ -226,10 +445,27 @@ func (r *ApiRequest) SetTimeout(timeout time.Duration) ExecutableApiRequest {
 	return r
 }
 
+// SetRetryPolicy sets the retry policy for the API request.
+//
+// The timeout set by SetTimeout covers all attempts, including the waits in between.
+//
+// Parameters:
+//   - policy: the retry policy, nil disables retries.
+//
+// Returns:
+//   - executableApiRequest: The modified apiRequest struct.
+func (r *ApiRequest) SetRetryPolicy(policy RetryPolicy) ExecutableApiRequest {
+	r.retryPolicy = policy
+	return r
+}
+
 type apiResponse interface {
 	Result() ([]byte, error)
 	Success() bool
 	SuccessResult() ([]byte, error)
+	StatusCode() int
+	Header() http.Header
+	Attempts() int
 }
 
 // Result returns the result of the HTTP response.
@@ -253,20 +489,35 @@ func (r *ApiRequest) Success() bool {
 
 // SuccessResult returns the response body and error from the HTTP request.
 //
-// It calls the Result() method to get the response body and checks if the
-// response status code is greater than or equal to http.StatusBadRequest.
-// If it is, it logs the request URL, status code, and response body, and
-// returns an error with the response body. Otherwise, it returns the response
-// body and nil error.
+// It checks if the response status code is greater than or equal to
+// http.StatusBadRequest. If it is, it returns an *HttpError describing the
+// failed response. Otherwise, it returns the response body and the request error.
 //
 // Returns:
 //   - []byte: The response body.
-//   - error: An error if the response status code is greater than or equal to
-//     http.StatusBadRequest, otherwise nil.
+//   - error: An *HttpError if the response status code is greater than or equal to
+//     http.StatusBadRequest, otherwise the request error.
 func (r *ApiRequest) SuccessResult() ([]byte, error) {
-	if r.apiResponseStatusCode >= http.StatusBadRequest {
-		err := errors.New(string(r.apiResponseData))
-		return nil, err
+	if httpErr := r.newHttpError(); httpErr != nil {
+		return nil, httpErr
 	}
 	return r.apiResponseData, r.apiResponseError
 }
+
+// StatusCode returns the status code of the HTTP response.
+func (r *ApiRequest) StatusCode() int {
+	return r.apiResponseStatusCode
+}
+
+// Header returns the headers of the HTTP response, nil when no response was received.
+func (r *ApiRequest) Header() http.Header {
+	if r.apiResponse == nil {
+		return nil
+	}
+	return r.apiResponse.Header
+}
+
+// Attempts returns the number of attempts made by the last Do call.
+func (r *ApiRequest) Attempts() int {
+	return r.attempts
+}
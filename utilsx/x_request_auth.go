@@ -0,0 +1,279 @@
+package utilsx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	OAUTH2_DEFAULT_EXPIRY_DELTA  time.Duration = 30 * time.Second
+	OAUTH2_DEFAULT_FETCH_TIMEOUT time.Duration = 10 * time.Second
+)
+
+// AuthProvider adds credentials to an outgoing request.
+type AuthProvider interface {
+	Apply(req *http.Request) error
+}
+
+// RefreshableAuthProvider is an AuthProvider whose credentials can expire early.
+//
+// Invalidate is called with the request that got a 401 response, so that the
+// provider can drop the credentials it used and fetch new ones.
+type RefreshableAuthProvider interface {
+	AuthProvider
+	Invalidate(req *http.Request)
+}
+
+type AuthProviderFunc func(req *http.Request) error
+
+// OAuth2Setting configures an OAuth2 client credentials provider.
+type OAuth2Setting struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	ExtraParams  url.Values    // additional token request parameters, such as audience
+	AuthInParams bool          // send the client credentials in the body instead of basic auth
+	ExpiryDelta  time.Duration // how long before its expiry a token is refreshed, at most half of its lifetime
+	Client       *HttpClient   // client fetching the tokens, a dedicated client without logging by default
+}
+
+// OAuth2ClientCredentials fetches and caches tokens with the OAuth2 client
+// credentials grant. Concurrent callers share a single token fetch.
+type OAuth2ClientCredentials struct {
+	setting OAuth2Setting
+	mu      sync.Mutex
+	token   string
+	refresh time.Time // when the cached token is refreshed, ExpiryDelta before its expiry
+	call    *oauth2TokenCall
+}
+
+type oauth2TokenCall struct {
+	done  chan struct{}
+	token string
+	err   error
+}
+
+type oauth2Token struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// Apply implements AuthProvider.
+func (f AuthProviderFunc) Apply(req *http.Request) error {
+	return f(req)
+}
+
+// BearerAuth sends a static bearer token in the Authorization header.
+func BearerAuth(token string) AuthProvider {
+	return AuthProviderFunc(func(req *http.Request) error {
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	})
+}
+
+// BasicAuth sends a username and password with HTTP basic authentication.
+func BasicAuth(username, password string) AuthProvider {
+	return AuthProviderFunc(func(req *http.Request) error {
+		req.SetBasicAuth(username, password)
+		return nil
+	})
+}
+
+// APIKeyHeader sends an API key in the given header.
+func APIKeyHeader(name, key string) AuthProvider {
+	return AuthProviderFunc(func(req *http.Request) error {
+		req.Header.Set(name, key)
+		return nil
+	})
+}
+
+// APIKeyQuery sends an API key in the given query parameter.
+func APIKeyQuery(name, key string) AuthProvider {
+	return AuthProviderFunc(func(req *http.Request) error {
+		query := req.URL.Query()
+		query.Set(name, key)
+		req.URL.RawQuery = query.Encode()
+		return nil
+	})
+}
+
+// AuthMiddleware applies the provider to every attempt of a request.
+//
+// When the provider is a RefreshableAuthProvider and the server answers 401,
+// the credentials are invalidated and the request is sent once more.
+func AuthMiddleware(provider AuthProvider) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			if err := provider.Apply(req); err != nil {
+				return nil, err
+			}
+			resp, err := next(req)
+			refreshable, ok := provider.(RefreshableAuthProvider)
+			if err != nil || !ok || resp.StatusCode != http.StatusUnauthorized {
+				return resp, err
+			}
+			// a streamed body has already been consumed
+			if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+				return resp, nil
+			}
+
+			refreshable.Invalidate(req)
+			retry := req.Clone(req.Context())
+			if req.GetBody != nil {
+				if retry.Body, err = req.GetBody(); err != nil {
+					return resp, nil
+				}
+			}
+			if err = provider.Apply(retry); err != nil {
+				return resp, nil
+			}
+			drainBody(resp.Body)
+			return next(retry)
+		}
+	}
+}
+
+// WithAuth authenticates every request of the client with the provider.
+func WithAuth(provider AuthProvider) HttpClientOption {
+	return WithMiddleware(AuthMiddleware(provider))
+}
+
+// SetAuth sets the provider authenticating the apiRequest, after the client middlewares.
+//
+// Parameters:
+//   - provider: the authentication provider.
+//
+// Returns:
+//   - executableApiRequest: The modified apiRequest struct.
+func (r *ApiRequest) SetAuth(provider AuthProvider) ExecutableApiRequest {
+	r.auth = provider
+	return r
+}
+
+// NewOAuth2ClientCredentials creates a new OAuth2 client credentials provider.
+//
+// It takes the OAuth2 setting.
+// It returns a pointer to the created provider.
+func NewOAuth2ClientCredentials(setting OAuth2Setting) *OAuth2ClientCredentials {
+	if setting.ExpiryDelta <= 0 {
+		setting.ExpiryDelta = OAUTH2_DEFAULT_EXPIRY_DELTA
+	}
+	if setting.Client == nil {
+		setting.Client = NewHttpClient(WithTimeout(OAUTH2_DEFAULT_FETCH_TIMEOUT))
+	}
+	return &OAuth2ClientCredentials{setting: setting}
+}
+
+// Apply implements AuthProvider.
+func (p *OAuth2ClientCredentials) Apply(req *http.Request) error {
+	token, err := p.Token(req.Context())
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// Invalidate implements RefreshableAuthProvider.
+//
+// The cached token is only dropped if req was sent with it, so that a burst
+// of 401 responses triggers a single refresh.
+func (p *OAuth2ClientCredentials) Invalidate(req *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if req.Header.Get("Authorization") == "Bearer "+p.token {
+		p.token = ""
+		p.refresh = time.Time{}
+	}
+}
+
+// Token returns a valid access token, fetching a new one when needed.
+//
+// Parameters:
+//   - ctx: bounds how long the caller waits, the shared fetch is not canceled with it.
+//
+// Returns:
+//   - string: the access token.
+//   - error: the fetch error or the context error.
+func (p *OAuth2ClientCredentials) Token(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	if p.token != "" && time.Now().Before(p.refresh) {
+		token := p.token
+		p.mu.Unlock()
+		return token, nil
+	}
+	call := p.call
+	if call == nil {
+		call = &oauth2TokenCall{done: make(chan struct{})}
+		p.call = call
+		go p.fetch(call)
+	}
+	p.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.token, call.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// fetch requests a new token and publishes it to the callers waiting on call.
+func (p *OAuth2ClientCredentials) fetch(call *oauth2TokenCall) {
+	token, refresh, err := p.requestToken(context.Background())
+
+	p.mu.Lock()
+	p.call = nil
+	if err == nil {
+		p.token, p.refresh = token, refresh
+	}
+	p.mu.Unlock()
+
+	call.token, call.err = token, err
+	close(call.done)
+}
+
+// requestToken sends the client credentials token request.
+//
+// It returns the access token, the time to refresh it and an error.
+func (p *OAuth2ClientCredentials) requestToken(ctx context.Context) (string, time.Time, error) {
+	req := p.setting.Client.NewRequest(p.setting.TokenURL).
+		SetHeader("Accept", CONTENT_TYPE_JSON).
+		SetFormValue("grant_type", "client_credentials")
+	if len(p.setting.Scopes) > 0 {
+		req.SetFormValue("scope", strings.Join(p.setting.Scopes, " "))
+	}
+	for key, values := range p.setting.ExtraParams {
+		for _, value := range values {
+			req.SetFormValue(key, value)
+		}
+	}
+	if p.setting.AuthInParams {
+		req.SetFormValue("client_id", p.setting.ClientID).SetFormValue("client_secret", p.setting.ClientSecret)
+	} else {
+		req.SetAuth(BasicAuth(url.QueryEscape(p.setting.ClientID), url.QueryEscape(p.setting.ClientSecret)))
+	}
+
+	start := time.Now()
+	token, err := DoJSONContext[oauth2Token](ctx, req, HTTP_METHOD_POST)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if token.AccessToken == "" {
+		return "", time.Time{}, errors.New("utilsx: oauth2 token response has no access_token")
+	}
+	// tokens without expires_in are refreshed on the first 401
+	if token.ExpiresIn <= 0 {
+		return token.AccessToken, start.Add(100 * 365 * 24 * time.Hour), nil
+	}
+	// a short-lived token is still cached for half of its lifetime
+	lifetime := time.Duration(token.ExpiresIn) * time.Second
+	return token.AccessToken, start.Add(lifetime - min(p.setting.ExpiryDelta, lifetime/2)), nil
+}
@@ -0,0 +1,108 @@
+package utilsx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestStaticAuthProviders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s|%s|%s", r.Header.Get("Authorization"), r.Header.Get("X-Api-Key"), r.URL.Query().Get("key"))
+	}))
+	defer server.Close()
+
+	cases := map[string]AuthProvider{
+		"Bearer token||":       BearerAuth("token"),
+		"Basic dXNlcjpwYXNz||": BasicAuth("user", "pass"),
+		"|secret|":             APIKeyHeader("X-Api-Key", "secret"),
+		"||secret":             APIKeyQuery("key", "secret"),
+	}
+	for expected, provider := range cases {
+		resp, err := NewHttpRequest(server.URL).SetAuth(provider).Do(HTTP_METHOD_GET)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data, _ := resp.Result(); string(data) != expected {
+			t.Fatalf("expected %q, got %q", expected, data)
+		}
+	}
+}
+
+func TestOAuth2ClientCredentials(t *testing.T) {
+	var fetches int32
+	var valid atomic.Value
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if user, pass, _ := r.BasicAuth(); user != "client" || pass != "secret" || r.PostForm.Get("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		token := fmt.Sprintf("token-%d", atomic.AddInt32(&fetches, 1))
+		valid.Store(token)
+		fmt.Fprintf(w, `{"access_token":%q,"token_type":"Bearer","expires_in":3600}`, token)
+	}))
+	defer tokenServer.Close()
+
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+valid.Load().(string) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer apiServer.Close()
+
+	provider := NewOAuth2ClientCredentials(OAuth2Setting{
+		TokenURL:     tokenServer.URL,
+		ClientID:     "client",
+		ClientSecret: "secret",
+	})
+	client := NewHttpClient(WithAuth(provider))
+
+	// 并发请求只获取一次token
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.NewRequest(apiServer.URL).Do(HTTP_METHOD_GET); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if fetches != 1 {
+		t.Fatalf("expected a single token fetch, got %d", fetches)
+	}
+
+	// token被服务端吊销后，收到401时刷新token并重试一次
+	valid.Store("revoked")
+	if _, err := client.NewRequest(apiServer.URL).Do(HTTP_METHOD_GET); err != nil {
+		t.Fatal(err)
+	}
+	if fetches != 2 {
+		t.Fatalf("expected a token refresh, got %d fetches", fetches)
+	}
+}
+
+func TestOAuth2ShortLivedToken(t *testing.T) {
+	var fetches int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"access_token":"token-%d","expires_in":10}`, atomic.AddInt32(&fetches, 1))
+	}))
+	defer tokenServer.Close()
+
+	// 有效期短于ExpiryDelta的token仍然缓存到有效期的一半
+	provider := NewOAuth2ClientCredentials(OAuth2Setting{TokenURL: tokenServer.URL, ClientID: "client", ClientSecret: "secret"})
+	for i := 0; i < 3; i++ {
+		if token, err := provider.Token(context.Background()); err != nil || token != "token-1" {
+			t.Fatalf("expected cached token, got %s %v", token, err)
+		}
+	}
+	if atomic.LoadInt32(&fetches) != 1 {
+		t.Fatalf("expected a single token fetch, got %d", fetches)
+	}
+}
@@ -0,0 +1,158 @@
+package utilsx
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sync"
+)
+
+const BATCH_DEFAULT_CONCURRENCY int = 8
+
+// ErrBatchAborted is the error of the requests never sent because a fail-fast batch failed.
+var ErrBatchAborted = errors.New("utilsx: batch aborted after a failed request")
+
+// BatchSetting configures DoBatch.
+type BatchSetting struct {
+	Concurrency        int  // maximum number of requests in flight, BATCH_DEFAULT_CONCURRENCY when 0
+	PerHostConcurrency int  // maximum number of requests in flight per host, 0 means no limit
+	FailFast           bool // cancel the batch on the first failed request
+}
+
+// BatchRequest is a request of a batch and the method it is sent with.
+type BatchRequest struct {
+	Request ExecutableApiRequest
+	Method  HttpMethod
+}
+
+// BatchResult is the outcome of a BatchRequest.
+type BatchResult struct {
+	Response apiResponse // nil when the request was never sent
+	Err      error
+}
+
+// DoBatch sends the requests concurrently and returns their results in input order.
+//
+// Every BatchRequest must hold its own ApiRequest. Canceling ctx cancels the
+// whole batch.
+//
+// Parameters:
+//   - ctx: the context shared by all the requests.
+//   - requests: the requests to send.
+//   - setting: the concurrency limits and failure mode.
+//
+// Returns:
+//   - []BatchResult: one result per request, in input order.
+//   - error: with FailFast, the error that aborted the batch, otherwise the
+//     joined errors of the failed requests.
+func DoBatch(ctx context.Context, requests []BatchRequest, setting BatchSetting) ([]BatchResult, error) {
+	if setting.Concurrency <= 0 {
+		setting.Concurrency = BATCH_DEFAULT_CONCURRENCY
+	}
+	batchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	slots := make(chan struct{}, setting.Concurrency)
+	hostSlots := make(map[string]chan struct{})
+	if setting.PerHostConcurrency > 0 {
+		for _, request := range requests {
+			host := batchHost(request.Request)
+			if _, ok := hostSlots[host]; !ok {
+				hostSlots[host] = make(chan struct{}, setting.PerHostConcurrency)
+			}
+		}
+	}
+
+	results := make([]BatchResult, len(requests))
+	var (
+		wg       sync.WaitGroup
+		failOnce sync.Once
+		failure  error
+	)
+	for i, request := range requests {
+		wg.Add(1)
+		go func(i int, request BatchRequest) {
+			defer wg.Done()
+			// the host slot is taken first, so that requests waiting for a busy
+			// host never hold a slot of the batch
+			hostSlot := hostSlots[batchHost(request.Request)]
+			if !acquireSlot(batchCtx, hostSlot) {
+				results[i].Err = batchAbortError(ctx)
+				return
+			}
+			defer releaseSlot(hostSlot)
+			if !acquireSlot(batchCtx, slots) {
+				results[i].Err = batchAbortError(ctx)
+				return
+			}
+			defer releaseSlot(slots)
+
+			results[i].Response, results[i].Err = request.Request.DoContext(batchCtx, request.Method)
+			if results[i].Err != nil && setting.FailFast {
+				failOnce.Do(func() {
+					failure = results[i].Err
+					cancel()
+				})
+			}
+		}(i, request)
+	}
+	wg.Wait()
+
+	if setting.FailFast {
+		if failure == nil && ctx.Err() != nil {
+			failure = batchAbortError(ctx)
+		}
+		return results, failure
+	}
+	var errs []error
+	for _, result := range results {
+		if result.Err != nil {
+			errs = append(errs, result.Err)
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+// batchHost returns the host a request is sent to, used for the per host limits.
+func batchHost(request ExecutableApiRequest) string {
+	if withUrl, ok := request.(interface{ GetUrl() string }); ok {
+		if parsed, err := url.Parse(withUrl.GetUrl()); err == nil {
+			return parsed.Host
+		}
+	}
+	return ""
+}
+
+// acquireSlot takes a slot of the semaphore, a nil semaphore has no limit.
+//
+// It returns false when ctx is done first.
+func acquireSlot(ctx context.Context, slots chan struct{}) bool {
+	if slots == nil {
+		return ctx.Err() == nil
+	}
+	select {
+	case slots <- struct{}{}:
+		if ctx.Err() != nil {
+			<-slots
+			return false
+		}
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// releaseSlot gives back a slot taken by acquireSlot.
+func releaseSlot(slots chan struct{}) {
+	if slots != nil {
+		<-slots
+	}
+}
+
+// batchAbortError returns the error of a request never sent.
+func batchAbortError(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return contextError(ctx, err)
+	}
+	return ErrBatchAborted
+}
@@ -0,0 +1,155 @@
+package utilsx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newConcurrencyServer 返回记录最大并发数的测试服务
+func newConcurrencyServer(inFlight, peak *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(inFlight, 1)
+		defer atomic.AddInt32(inFlight, -1)
+		for {
+			previous := atomic.LoadInt32(peak)
+			if current <= previous || atomic.CompareAndSwapInt32(peak, previous, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		if r.URL.Query().Get("fail") != "" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		w.Write([]byte(r.URL.Query().Get("id")))
+	}))
+}
+
+func TestDoBatchOrderAndConcurrency(t *testing.T) {
+	var inFlight, peak int32
+	server := newConcurrencyServer(&inFlight, &peak)
+	defer server.Close()
+
+	requests := make([]BatchRequest, 20)
+	for i := range requests {
+		requests[i] = BatchRequest{
+			Request: NewHttpClient().NewRequest(server.URL).SetQueryParam("id", strconv.Itoa(i)),
+			Method:  HTTP_METHOD_GET,
+		}
+	}
+	results, err := DoBatch(context.Background(), requests, BatchSetting{Concurrency: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 结果按输入顺序返回，并发数不超过限制
+	for i, result := range results {
+		if result.Err != nil {
+			t.Fatal(result.Err)
+		}
+		if body, _ := result.Response.Result(); string(body) != strconv.Itoa(i) {
+			t.Fatalf("result %d out of order: %s", i, body)
+		}
+	}
+	if atomic.LoadInt32(&peak) > 4 {
+		t.Fatalf("concurrency limit exceeded: %d", peak)
+	}
+}
+
+func TestDoBatchPerHostConcurrency(t *testing.T) {
+	var slowInFlight, slowPeak, fastInFlight, fastPeak int32
+	slow := newConcurrencyServer(&slowInFlight, &slowPeak)
+	defer slow.Close()
+	fast := newConcurrencyServer(&fastInFlight, &fastPeak)
+	defer fast.Close()
+
+	var requests []BatchRequest
+	for i := 0; i < 8; i++ {
+		requests = append(requests,
+			BatchRequest{Request: NewHttpClient().NewRequest(slow.URL), Method: HTTP_METHOD_GET},
+			BatchRequest{Request: NewHttpClient().NewRequest(fast.URL), Method: HTTP_METHOD_GET},
+		)
+	}
+
+	// 每个主机的并发数单独限制
+	if _, err := DoBatch(context.Background(), requests, BatchSetting{Concurrency: 8, PerHostConcurrency: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(&slowPeak) > 2 || atomic.LoadInt32(&fastPeak) > 2 {
+		t.Fatalf("per host limit exceeded: %d %d", slowPeak, fastPeak)
+	}
+}
+
+func TestDoBatchFailureModes(t *testing.T) {
+	var inFlight, peak int32
+	server := newConcurrencyServer(&inFlight, &peak)
+	defer server.Close()
+
+	newRequests := func(failures int) []BatchRequest {
+		var requests []BatchRequest
+		for i := 0; i < 6; i++ {
+			request := NewHttpClient().NewRequest(server.URL)
+			if i < failures {
+				request.SetQueryParam("fail", "1")
+			}
+			requests = append(requests, BatchRequest{Request: request, Method: HTTP_METHOD_GET})
+		}
+		return requests
+	}
+
+	// 收集全部结果：失败请求不影响其他请求
+	results, err := DoBatch(context.Background(), newRequests(1), BatchSetting{Concurrency: 1})
+	var httpErr *HttpError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("expected joined http error, got %v", err)
+	}
+	for _, result := range results[1:] {
+		if result.Err != nil {
+			t.Fatalf("unexpected error %v", result.Err)
+		}
+	}
+
+	// 快速失败：第一个失败后取消剩余请求
+	results, err = DoBatch(context.Background(), newRequests(6), BatchSetting{Concurrency: 1, FailFast: true})
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("expected http error, got %v", err)
+	}
+	aborted := 0
+	for _, result := range results {
+		if errors.Is(result.Err, ErrBatchAborted) {
+			aborted++
+		}
+	}
+	if aborted != 5 {
+		t.Fatalf("expected 5 aborted requests, got %d", aborted)
+	}
+}
+
+func TestDoBatchContextCanceled(t *testing.T) {
+	var inFlight, peak int32
+	server := newConcurrencyServer(&inFlight, &peak)
+	defer server.Close()
+
+	requests := make([]BatchRequest, 10)
+	for i := range requests {
+		requests[i] = BatchRequest{Request: NewHttpClient().NewRequest(server.URL), Method: HTTP_METHOD_GET}
+	}
+
+	// 取消共享的context会取消整个批次
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	results, err := DoBatch(ctx, requests, BatchSetting{Concurrency: 1, FailFast: true})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected canceled batch, got %v", err)
+	}
+	for i, result := range results {
+		if !errors.Is(result.Err, ErrRequestCanceled) {
+			t.Fatalf("request %d: expected canceled error, got %v", i, result.Err)
+		}
+	}
+}
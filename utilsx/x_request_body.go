@@ -0,0 +1,275 @@
+package utilsx
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"sort"
+)
+
+const (
+	CONTENT_TYPE_JSON  string = "application/json"
+	CONTENT_TYPE_XML   string = "application/xml"
+	CONTENT_TYPE_FORM  string = "application/x-www-form-urlencoded"
+	CONTENT_TYPE_OCTET string = "application/octet-stream"
+)
+
+type bodyMode int
+
+const (
+	bodyModeJSONMap   bodyMode = iota // key-value pairs set by SetBody, marshaled as JSON
+	bodyModeForm                      // application/x-www-form-urlencoded values
+	bodyModeMultipart                 // multipart/form-data values and files
+	bodyModeRaw                       // raw bytes with a custom content type
+	bodyModeStream                    // single-use io.Reader with a custom content type
+	bodyModeJSON                      // arbitrary value marshaled as JSON
+	bodyModeXML                       // arbitrary value marshaled as XML
+)
+
+type multipartFile struct {
+	field    string
+	filename string
+	reader   io.Reader
+	data     []byte // content read from reader by the first encoding
+	read     bool   // whether reader was read into data
+}
+
+// requestBody is an encoded request body ready to be sent.
+type requestBody struct {
+	data            []byte    // buffered body, sent again on every attempt
+	stream          io.Reader // streamed body, can only be sent once
+	contentType     string
+	contentEncoding string // compression of the body, empty when uncompressed
+}
+
+// SetFormValue adds a value to the form body of the apiRequest.
+//
+// The body is sent as application/x-www-form-urlencoded, or as the plain
+// fields of a multipart/form-data body once a file is added.
+//
+// Parameters:
+//   - key: the form field name.
+//   - value: the form field value.
+//
+// Returns:
+//   - executableApiRequest: The modified apiRequest struct.
+func (r *ApiRequest) SetFormValue(key, value string) ExecutableApiRequest {
+	if r.bodyMode != bodyModeMultipart {
+		r.bodyMode = bodyModeForm
+	}
+	r.form.Add(key, value)
+	return r
+}
+
+// SetMultipartFile adds a file part to the multipart/form-data body of the apiRequest.
+//
+// The reader is read once, when the request is first sent or rendered by
+// Curl, and its content is kept to be sent again by retries and later Do
+// calls. The whole multipart body, files included, is therefore buffered in
+// memory: upload large files with SetBodyReader.
+//
+// Parameters:
+//   - field: the form field name.
+//   - filename: the file name reported to the server.
+//   - reader: the file content.
+//
+// Returns:
+//   - executableApiRequest: The modified apiRequest struct.
+func (r *ApiRequest) SetMultipartFile(field, filename string, reader io.Reader) ExecutableApiRequest {
+	r.bodyMode = bodyModeMultipart
+	r.files = append(r.files, multipartFile{field: field, filename: filename, reader: reader})
+	return r
+}
+
+// SetRawBody sets the raw bytes sent as the body of the apiRequest.
+//
+// Parameters:
+//   - data: the body bytes.
+//   - contentType: the body content type, application/octet-stream when empty.
+//
+// Returns:
+//   - executableApiRequest: The modified apiRequest struct.
+func (r *ApiRequest) SetRawBody(data []byte, contentType string) ExecutableApiRequest {
+	r.bodyMode = bodyModeRaw
+	r.rawBody = data
+	r.contentType = contentType
+	return r
+}
+
+// SetBodyReader sets a stream sent as the body of the apiRequest.
+//
+// The stream is not buffered, so it can only be used by one Do call and the
+// request is never retried.
+//
+// Parameters:
+//   - reader: the body stream.
+//   - contentType: the body content type, application/octet-stream when empty.
+//
+// Returns:
+//   - executableApiRequest: The modified apiRequest struct.
+func (r *ApiRequest) SetBodyReader(reader io.Reader, contentType string) ExecutableApiRequest {
+	r.bodyMode = bodyModeStream
+	r.bodyReader = reader
+	r.contentType = contentType
+	return r
+}
+
+// SetJSONBody sets a value, such as a struct or a slice, marshaled as the JSON body of the apiRequest.
+//
+// Parameters:
+//   - value: the value to marshal.
+//
+// Returns:
+//   - executableApiRequest: The modified apiRequest struct.
+func (r *ApiRequest) SetJSONBody(value interface{}) ExecutableApiRequest {
+	r.bodyMode = bodyModeJSON
+	r.bodyValue = value
+	return r
+}
+
+// SetXMLBody sets a value marshaled as the XML body of the apiRequest.
+//
+// Parameters:
+//   - value: the value to marshal.
+//
+// Returns:
+//   - executableApiRequest: The modified apiRequest struct.
+func (r *ApiRequest) SetXMLBody(value interface{}) ExecutableApiRequest {
+	r.bodyMode = bodyModeXML
+	r.bodyValue = value
+	return r
+}
+
+// SetForceBody makes GET and HEAD requests send their body, which they omit by default.
+//
+// Parameters:
+//   - force: whether to send the body for GET and HEAD requests.
+//
+// Returns:
+//   - executableApiRequest: The modified apiRequest struct.
+func (r *ApiRequest) SetForceBody(force bool) ExecutableApiRequest {
+	r.forceBody = force
+	return r
+}
+
+// encodeBody encodes the body of the apiRequest according to its body mode.
+//
+// It takes the method of the request, GET and HEAD requests get no body unless
+// SetForceBody was called.
+// It returns the encoded body, nil when no body must be sent, and an error.
+func (r *ApiRequest) encodeBody(method HttpMethod) (*requestBody, error) {
+	if (method == HTTP_METHOD_GET || method == HTTP_METHOD_HEAD) && !r.forceBody {
+		return nil, nil
+	}
+	switch r.bodyMode {
+	case bodyModeForm:
+		return &requestBody{data: []byte(r.form.Encode()), contentType: CONTENT_TYPE_FORM}, nil
+	case bodyModeMultipart:
+		return r.encodeMultipart()
+	case bodyModeRaw:
+		return &requestBody{data: r.rawBody, contentType: contentTypeOrOctet(r.contentType)}, nil
+	case bodyModeStream:
+		return &requestBody{stream: r.bodyReader, contentType: contentTypeOrOctet(r.contentType)}, nil
+	case bodyModeJSON:
+		data, err := json.Marshal(r.bodyValue)
+		if err != nil {
+			return nil, err
+		}
+		return &requestBody{data: data, contentType: CONTENT_TYPE_JSON}, nil
+	case bodyModeXML:
+		data, err := xml.Marshal(r.bodyValue)
+		if err != nil {
+			return nil, err
+		}
+		return &requestBody{data: data, contentType: CONTENT_TYPE_XML}, nil
+	}
+	data, err := json.Marshal(r.body)
+	if err != nil {
+		return nil, err
+	}
+	return &requestBody{data: data, contentType: CONTENT_TYPE_JSON}, nil
+}
+
+// encodeMultipart buffers the form values and files as a multipart/form-data body.
+//
+// The file readers are only read by the first call, later calls reuse their content.
+func (r *ApiRequest) encodeMultipart() (*requestBody, error) {
+	var buffer bytes.Buffer
+	writer := multipart.NewWriter(&buffer)
+
+	keys := make([]string, 0, len(r.form))
+	for key := range r.form {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range r.form[key] {
+			if err := writer.WriteField(key, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for i := range r.files {
+		file := &r.files[i]
+		if !file.read {
+			data, err := io.ReadAll(file.reader)
+			if err != nil {
+				return nil, err
+			}
+			file.data, file.read = data, true
+		}
+		part, err := writer.CreateFormFile(file.field, file.filename)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = part.Write(file.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return &requestBody{data: buffer.Bytes(), contentType: writer.FormDataContentType()}, nil
+}
+
+// reader returns a fresh reader over the body, nil when there is no body.
+func (b *requestBody) reader() io.Reader {
+	if b == nil {
+		return nil
+	}
+	if b.stream != nil {
+		return b.stream
+	}
+	return bytes.NewReader(b.data)
+}
+
+// replayable reports whether the body can be sent again on a retry.
+func (b *requestBody) replayable() bool {
+	return b == nil || b.stream == nil
+}
+
+// setHeaders sets the Content-Encoding header of a compressed body, and the
+// Content-Type header unless the caller already set one.
+func (b *requestBody) setHeaders(header http.Header) {
+	if b == nil {
+		return
+	}
+	if b.contentEncoding != "" {
+		header.Set("Content-Encoding", b.contentEncoding)
+	}
+	if b.contentType == "" || header.Get("Content-Type") != "" {
+		return
+	}
+	header.Set("Content-Type", b.contentType)
+}
+
+// contentTypeOrOctet returns contentType, or application/octet-stream when it is empty.
+func contentTypeOrOctet(contentType string) string {
+	if contentType == "" {
+		return CONTENT_TYPE_OCTET
+	}
+	return contentType
+}
@@ -0,0 +1,116 @@
+package utilsx
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type testBodyRequest struct {
+	method      string
+	contentType string
+	body        string
+	form        map[string][]string
+	files       map[string]string
+}
+
+func newTestBodyServer(t *testing.T, received *testBodyRequest) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.method = r.Method
+		received.contentType = r.Header.Get("Content-Type")
+		if strings.HasPrefix(received.contentType, "multipart/form-data") {
+			if err := r.ParseMultipartForm(1 << 20); err != nil {
+				t.Error(err)
+			}
+			received.form = r.MultipartForm.Value
+			received.files = make(map[string]string)
+			for field, headers := range r.MultipartForm.File {
+				file, _ := headers[0].Open()
+				content, _ := io.ReadAll(file)
+				received.files[field] = headers[0].Filename + ":" + string(content)
+			}
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		received.body = string(body)
+	}))
+}
+
+func TestRequestBodyModes(t *testing.T) {
+	var received testBodyRequest
+	server := newTestBodyServer(t, &received)
+	defer server.Close()
+
+	// GET请求默认不发送请求体
+	NewHttpRequest(server.URL).SetBody("name", "golix").Do(HTTP_METHOD_GET)
+	if received.body != "" || received.contentType != "" {
+		t.Fatalf("GET must not send a body, got %q", received.body)
+	}
+	NewHttpRequest(server.URL).SetBody("name", "golix").SetForceBody(true).Do(HTTP_METHOD_GET)
+	if received.body != `{"name":"golix"}` || received.contentType != CONTENT_TYPE_JSON {
+		t.Fatalf("unexpected forced GET body %q %q", received.body, received.contentType)
+	}
+
+	NewHttpRequest(server.URL).SetFormValue("ids", "1").SetFormValue("ids", "2").Do(HTTP_METHOD_POST)
+	if received.body != "ids=1&ids=2" || received.contentType != CONTENT_TYPE_FORM {
+		t.Fatalf("unexpected form body %q %q", received.body, received.contentType)
+	}
+
+	NewHttpRequest(server.URL).SetRawBody([]byte("a,b"), "text/csv").Do(HTTP_METHOD_PUT)
+	if received.body != "a,b" || received.contentType != "text/csv" {
+		t.Fatalf("unexpected raw body %q %q", received.body, received.contentType)
+	}
+
+	NewHttpRequest(server.URL).SetBodyReader(strings.NewReader("stream"), "").Do(HTTP_METHOD_POST)
+	if received.body != "stream" || received.contentType != CONTENT_TYPE_OCTET {
+		t.Fatalf("unexpected stream body %q %q", received.body, received.contentType)
+	}
+
+	users := []testJsonUser{{Id: 1, Name: "golix"}}
+	NewHttpRequest(server.URL).SetJSONBody(users).Do(HTTP_METHOD_POST)
+	var decoded []testJsonUser
+	if err := json.Unmarshal([]byte(received.body), &decoded); err != nil || len(decoded) != 1 {
+		t.Fatalf("unexpected json body %q", received.body)
+	}
+
+	type testXmlUser struct {
+		Name string `xml:"name"`
+	}
+	NewHttpRequest(server.URL).SetXMLBody(testXmlUser{Name: "golix"}).Do(HTTP_METHOD_POST)
+	if received.body != "<testXmlUser><name>golix</name></testXmlUser>" || received.contentType != CONTENT_TYPE_XML {
+		t.Fatalf("unexpected xml body %q %q", received.body, received.contentType)
+	}
+
+	// 最后调用的请求体设置生效，SetBody不会被之前的设置忽略
+	NewHttpRequest(server.URL).SetFormValue("a", "1").SetBody("name", "golix").Do(HTTP_METHOD_POST)
+	if received.body != `{"name":"golix"}` || received.contentType != CONTENT_TYPE_JSON {
+		t.Fatalf("unexpected mixed body %q %q", received.body, received.contentType)
+	}
+	NewHttpRequest(server.URL).SetBody("name", "golix").SetRawBody([]byte("a,b"), "text/csv").Do(HTTP_METHOD_POST)
+	if received.body != "a,b" {
+		t.Fatalf("unexpected mixed body %q", received.body)
+	}
+
+	// 显式设置的Content-Type优先
+	NewHttpRequest(server.URL).SetHeader("Content-Type", "application/vnd.api+json").SetBody("a", 1).Do(HTTP_METHOD_POST)
+	if received.contentType != "application/vnd.api+json" {
+		t.Fatalf("explicit content type overridden: %q", received.contentType)
+	}
+}
+
+func TestRequestMultipartBody(t *testing.T) {
+	var received testBodyRequest
+	server := newTestBodyServer(t, &received)
+	defer server.Close()
+
+	NewHttpRequest(server.URL).
+		SetFormValue("type", "avatar").
+		SetMultipartFile("file", "avatar.txt", strings.NewReader("content")).
+		Do(HTTP_METHOD_POST)
+	if received.form["type"][0] != "avatar" || received.files["file"] != "avatar.txt:content" {
+		t.Fatalf("unexpected multipart body %+v", received)
+	}
+}
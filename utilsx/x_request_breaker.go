@@ -0,0 +1,302 @@
+package utilsx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type CircuitState int
+
+const (
+	CIRCUIT_STATE_CLOSED CircuitState = iota
+	CIRCUIT_STATE_OPEN
+	CIRCUIT_STATE_HALF_OPEN
+)
+
+const (
+	CIRCUIT_DEFAULT_CONSECUTIVE_FAILURES int           = 5
+	CIRCUIT_DEFAULT_MIN_REQUESTS         int           = 20
+	CIRCUIT_DEFAULT_WINDOW               time.Duration = 10 * time.Second
+	CIRCUIT_DEFAULT_COOL_DOWN            time.Duration = 30 * time.Second
+	CIRCUIT_DEFAULT_HALF_OPEN_REQUESTS   int           = 1
+)
+
+// ErrCircuitOpen matches every CircuitOpenError with errors.Is.
+var ErrCircuitOpen = errors.New("utilsx: circuit breaker open")
+
+// CircuitBreakerSetting configures a CircuitBreaker. Zero values take the defaults.
+type CircuitBreakerSetting struct {
+	ConsecutiveFailures int           // consecutive failures tripping the breaker, -1 disables
+	FailureRate         float64       // failure ratio in a window tripping the breaker, 0 disables
+	MinRequests         int           // requests needed in a window before FailureRate applies
+	Window              time.Duration // length of the windows FailureRate is computed over
+	CoolDown            time.Duration // time spent open before letting probes through
+	HalfOpenRequests    int           // probes let through while half-open, all must succeed to close
+
+	// IsFailure tells whether a call counts as a failure. By default transport
+	// errors, except cancellation by the caller, and 429 or 5xx responses do.
+	IsFailure func(resp *http.Response, err error) bool
+	// OnStateChange is called after the breaker of a host changes state.
+	OnStateChange func(host string, from, to CircuitState)
+}
+
+// CircuitBreaker keeps one circuit per host and fails requests fast while the
+// circuit of their host is open.
+type CircuitBreaker struct {
+	setting CircuitBreakerSetting
+	mu      sync.Mutex
+	hosts   map[string]*hostCircuit
+	now     func() time.Time
+}
+
+// CircuitOpenError is returned instead of sending a request whose host circuit is open.
+type CircuitOpenError struct {
+	Host       string        // host of the request
+	State      CircuitState  // state of the circuit, open or half-open with all probes in flight
+	RetryAfter time.Duration // time left before the circuit lets probes through
+}
+
+type hostCircuit struct {
+	state               CircuitState
+	consecutiveFailures int
+	windowStart         time.Time
+	requests            int
+	failures            int
+	openedAt            time.Time
+	probes              int // probes in flight while half-open
+	probeSuccesses      int
+}
+
+type circuitOutcome int
+
+const (
+	circuitOutcomeSuccess circuitOutcome = iota
+	circuitOutcomeFailure
+	circuitOutcomeIgnored
+)
+
+type circuitTransition struct {
+	host     string
+	from, to CircuitState
+}
+
+// NewCircuitBreaker creates a new CircuitBreaker.
+//
+// It takes the breaker setting, zero fields are replaced by the CIRCUIT_DEFAULT_* values.
+// It returns a pointer to the created CircuitBreaker.
+func NewCircuitBreaker(setting CircuitBreakerSetting) *CircuitBreaker {
+	if setting.ConsecutiveFailures == 0 {
+		setting.ConsecutiveFailures = CIRCUIT_DEFAULT_CONSECUTIVE_FAILURES
+	}
+	if setting.MinRequests <= 0 {
+		setting.MinRequests = CIRCUIT_DEFAULT_MIN_REQUESTS
+	}
+	if setting.Window <= 0 {
+		setting.Window = CIRCUIT_DEFAULT_WINDOW
+	}
+	if setting.CoolDown <= 0 {
+		setting.CoolDown = CIRCUIT_DEFAULT_COOL_DOWN
+	}
+	if setting.HalfOpenRequests <= 0 {
+		setting.HalfOpenRequests = CIRCUIT_DEFAULT_HALF_OPEN_REQUESTS
+	}
+	if setting.IsFailure == nil {
+		setting.IsFailure = isCircuitFailure
+	}
+	return &CircuitBreaker{
+		setting: setting,
+		hosts:   make(map[string]*hostCircuit),
+		now:     time.Now,
+	}
+}
+
+// WithCircuitBreaker protects every request of the client with the circuit breaker.
+func WithCircuitBreaker(breaker *CircuitBreaker) HttpClientOption {
+	return WithMiddleware(breaker.Middleware())
+}
+
+// Middleware returns a middleware guarding every attempt with the circuit of its host.
+func (cb *CircuitBreaker) Middleware() Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			host := req.URL.Host
+			probe, err := cb.allow(host)
+			if err != nil {
+				return nil, err
+			}
+			resp, err := next(req)
+			outcome := circuitOutcomeSuccess
+			if err != nil && req.Context().Err() != nil {
+				// the caller gave up, this says nothing about the host
+				outcome = circuitOutcomeIgnored
+			} else if cb.setting.IsFailure(resp, err) {
+				outcome = circuitOutcomeFailure
+			}
+			cb.report(host, probe, outcome)
+			return resp, err
+		}
+	}
+}
+
+// State returns the current state of the circuit of host.
+func (cb *CircuitBreaker) State(host string) CircuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	circuit, ok := cb.hosts[host]
+	if !ok {
+		return CIRCUIT_STATE_CLOSED
+	}
+	if circuit.state == CIRCUIT_STATE_OPEN && cb.now().Sub(circuit.openedAt) >= cb.setting.CoolDown {
+		return CIRCUIT_STATE_HALF_OPEN
+	}
+	return circuit.state
+}
+
+// allow checks whether a request to host may be sent.
+//
+// It returns whether the request is a half-open probe, and a
+// *CircuitOpenError when the request must fail fast.
+func (cb *CircuitBreaker) allow(host string) (bool, error) {
+	var transitions []circuitTransition
+	defer func() { cb.notify(transitions) }()
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	circuit := cb.circuit(host)
+	now := cb.now()
+	if circuit.state == CIRCUIT_STATE_OPEN {
+		if elapsed := now.Sub(circuit.openedAt); elapsed < cb.setting.CoolDown {
+			return false, &CircuitOpenError{Host: host, State: CIRCUIT_STATE_OPEN, RetryAfter: cb.setting.CoolDown - elapsed}
+		}
+		transitions = append(transitions, circuit.setState(host, CIRCUIT_STATE_HALF_OPEN, now))
+	}
+	if circuit.state == CIRCUIT_STATE_HALF_OPEN {
+		if circuit.probes+circuit.probeSuccesses >= cb.setting.HalfOpenRequests {
+			return false, &CircuitOpenError{Host: host, State: CIRCUIT_STATE_HALF_OPEN}
+		}
+		circuit.probes++
+		return true, nil
+	}
+	return false, nil
+}
+
+// report records the outcome of a request to host.
+func (cb *CircuitBreaker) report(host string, probe bool, outcome circuitOutcome) {
+	var transitions []circuitTransition
+	defer func() { cb.notify(transitions) }()
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	circuit := cb.circuit(host)
+	now := cb.now()
+
+	if probe {
+		if circuit.state != CIRCUIT_STATE_HALF_OPEN {
+			return
+		}
+		circuit.probes--
+		switch outcome {
+		case circuitOutcomeFailure:
+			transitions = append(transitions, circuit.setState(host, CIRCUIT_STATE_OPEN, now))
+		case circuitOutcomeSuccess:
+			circuit.probeSuccesses++
+			if circuit.probeSuccesses >= cb.setting.HalfOpenRequests {
+				transitions = append(transitions, circuit.setState(host, CIRCUIT_STATE_CLOSED, now))
+			}
+		}
+		return
+	}
+	if circuit.state != CIRCUIT_STATE_CLOSED || outcome == circuitOutcomeIgnored {
+		return
+	}
+
+	if now.Sub(circuit.windowStart) >= cb.setting.Window {
+		circuit.windowStart, circuit.requests, circuit.failures = now, 0, 0
+	}
+	circuit.requests++
+	if outcome == circuitOutcomeSuccess {
+		circuit.consecutiveFailures = 0
+		return
+	}
+	circuit.failures++
+	circuit.consecutiveFailures++
+
+	tripped := cb.setting.ConsecutiveFailures > 0 && circuit.consecutiveFailures >= cb.setting.ConsecutiveFailures
+	if cb.setting.FailureRate > 0 && circuit.requests >= cb.setting.MinRequests &&
+		float64(circuit.failures)/float64(circuit.requests) >= cb.setting.FailureRate {
+		tripped = true
+	}
+	if tripped {
+		transitions = append(transitions, circuit.setState(host, CIRCUIT_STATE_OPEN, now))
+	}
+}
+
+// circuit returns the circuit of host, creating it on first use. cb.mu must be held.
+func (cb *CircuitBreaker) circuit(host string) *hostCircuit {
+	circuit, ok := cb.hosts[host]
+	if !ok {
+		circuit = &hostCircuit{windowStart: cb.now()}
+		cb.hosts[host] = circuit
+	}
+	return circuit
+}
+
+// notify calls OnStateChange for every transition, outside of the lock.
+func (cb *CircuitBreaker) notify(transitions []circuitTransition) {
+	if cb.setting.OnStateChange == nil {
+		return
+	}
+	for _, transition := range transitions {
+		cb.setting.OnStateChange(transition.host, transition.from, transition.to)
+	}
+}
+
+// setState moves the circuit to state and resets the counters of the new state.
+func (c *hostCircuit) setState(host string, state CircuitState, now time.Time) circuitTransition {
+	transition := circuitTransition{host: host, from: c.state, to: state}
+	c.state = state
+	c.probes, c.probeSuccesses = 0, 0
+	switch state {
+	case CIRCUIT_STATE_OPEN:
+		c.openedAt = now
+	case CIRCUIT_STATE_CLOSED:
+		c.consecutiveFailures, c.requests, c.failures = 0, 0, 0
+		c.windowStart = now
+	}
+	return transition
+}
+
+// String returns the name of the state.
+func (s CircuitState) String() string {
+	switch s {
+	case CIRCUIT_STATE_CLOSED:
+		return "closed"
+	case CIRCUIT_STATE_OPEN:
+		return "open"
+	case CIRCUIT_STATE_HALF_OPEN:
+		return "half-open"
+	}
+	return fmt.Sprintf("CircuitState(%d)", int(s))
+}
+
+// Error implements the error interface.
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("utilsx: circuit breaker %s for host %s", e.State, e.Host)
+}
+
+// Is makes errors.Is(err, ErrCircuitOpen) match.
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// isCircuitFailure is the default CircuitBreakerSetting.IsFailure.
+func isCircuitFailure(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled)
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+}
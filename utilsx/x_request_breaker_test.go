@@ -0,0 +1,95 @@
+package utilsx
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var healthy atomic.Bool
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	host := mustHost(t, server.URL)
+
+	var transitions []string
+	now := time.Now()
+	breaker := NewCircuitBreaker(CircuitBreakerSetting{
+		ConsecutiveFailures: 3,
+		CoolDown:            time.Minute,
+		OnStateChange: func(host string, from, to CircuitState) {
+			transitions = append(transitions, from.String()+"->"+to.String())
+		},
+	})
+	breaker.now = func() time.Time { return now }
+	client := NewHttpClient(WithCircuitBreaker(breaker))
+
+	for i := 0; i < 3; i++ {
+		client.NewRequest(server.URL).Do(HTTP_METHOD_GET)
+	}
+	if breaker.State(host) != CIRCUIT_STATE_OPEN {
+		t.Fatalf("expected open circuit, got %s", breaker.State(host))
+	}
+
+	// 熔断打开后快速失败，不再请求下游
+	_, err := client.NewRequest(server.URL).Do(HTTP_METHOD_GET)
+	var openErr *CircuitOpenError
+	if !errors.As(err, &openErr) || !errors.Is(err, ErrCircuitOpen) || openErr.Host != host || calls != 3 {
+		t.Fatalf("expected fast failure, got %v after %d calls", err, calls)
+	}
+
+	// 冷却结束后进入半开状态，探测请求成功则关闭熔断
+	now = now.Add(time.Minute)
+	healthy.Store(true)
+	if _, err = client.NewRequest(server.URL).Do(HTTP_METHOD_GET); err != nil {
+		t.Fatal(err)
+	}
+	if breaker.State(host) != CIRCUIT_STATE_CLOSED {
+		t.Fatalf("expected closed circuit, got %s", breaker.State(host))
+	}
+	expected := []string{"closed->open", "open->half-open", "half-open->closed"}
+	if len(transitions) != len(expected) {
+		t.Fatalf("unexpected transitions %v", transitions)
+	}
+	for i := range expected {
+		if transitions[i] != expected[i] {
+			t.Fatalf("unexpected transitions %v", transitions)
+		}
+	}
+}
+
+func TestCircuitBreakerFailureRate(t *testing.T) {
+	breaker := NewCircuitBreaker(CircuitBreakerSetting{
+		ConsecutiveFailures: -1,
+		FailureRate:         0.5,
+		MinRequests:         4,
+	})
+	for _, outcome := range []circuitOutcome{circuitOutcomeFailure, circuitOutcomeSuccess, circuitOutcomeSuccess} {
+		breaker.report("partner", false, outcome)
+	}
+	if breaker.State("partner") != CIRCUIT_STATE_CLOSED {
+		t.Fatal("failure rate must not apply below MinRequests")
+	}
+	breaker.report("partner", false, circuitOutcomeFailure)
+	if breaker.State("partner") != CIRCUIT_STATE_OPEN {
+		t.Fatalf("expected open circuit, got %s", breaker.State("partner"))
+	}
+}
+
+func mustHost(t *testing.T, address string) string {
+	Url, err := url.Parse(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Url.Host
+}
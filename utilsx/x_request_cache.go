@@ -0,0 +1,341 @@
+package utilsx
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	HTTP_CACHE_DEFAULT_CAPACITY int           = 1024
+	HTTP_CACHE_MAX_BODY_SIZE    int64         = 1024 * 1024 // larger responses are never cached
+	HTTP_CACHE_VALIDATOR_TTL    time.Duration = 24 * time.Hour
+)
+
+// CacheStore stores the cached responses of an HttpCache.
+//
+// Keys are hashes of the request method and URL. redisx.HttpCacheStore
+// implements it to share the cache between replicas.
+type CacheStore interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
+// HttpCache is a private HTTP cache following the Cache-Control, Expires,
+// ETag and Last-Modified headers of the responses.
+type HttpCache struct {
+	store CacheStore
+	now   func() time.Time
+}
+
+type cachedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Status     string      `json:"status"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+	Vary       http.Header `json:"vary,omitempty"` // request header values the response varies on
+	StoredAt   time.Time   `json:"stored_at"`
+	Expires    time.Time   `json:"expires"` // end of the freshness lifetime
+}
+
+type lruCacheStore struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[string]*list.Element
+	order    *list.List
+}
+
+type lruCacheItem struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// NewHttpCache creates a new HttpCache.
+//
+// It takes the cache store, an in-memory LRU store when nil.
+// It returns a pointer to the created HttpCache.
+func NewHttpCache(store CacheStore) *HttpCache {
+	if store == nil {
+		store = NewLRUCacheStore(HTTP_CACHE_DEFAULT_CAPACITY)
+	}
+	return &HttpCache{store: store, now: time.Now}
+}
+
+// NewLRUCacheStore creates an in-memory CacheStore keeping the capacity most recently used responses.
+func NewLRUCacheStore(capacity int) CacheStore {
+	if capacity <= 0 {
+		capacity = HTTP_CACHE_DEFAULT_CAPACITY
+	}
+	return &lruCacheStore{capacity: capacity, items: make(map[string]*list.Element), order: list.New()}
+}
+
+// WithCache caches the GET responses of the client.
+//
+// The cache key is the request URL, so a cache must not be shared between
+// clients sending different credentials for the same URLs.
+func WithCache(cache *HttpCache) HttpClientOption {
+	return WithMiddleware(cache.Middleware())
+}
+
+// Middleware returns a middleware serving GET requests from the cache.
+//
+// Fresh responses are served without a request. Stale responses with an ETag
+// or Last-Modified header are revalidated with If-None-Match and
+// If-Modified-Since, and served from the cache on 304 Not Modified.
+// Requests with their own conditional headers bypass the cache.
+func (c *HttpCache) Middleware() Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			requestControl := parseCacheControl(req.Header)
+			if req.Method != http.MethodGet || hasDirective(requestControl, "no-store") ||
+				req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != "" || req.Header.Get("Range") != "" {
+				return next(req)
+			}
+			ctx := req.Context()
+			key := cacheKey(req)
+
+			entry := c.load(ctx, key, req)
+			if entry != nil {
+				maxAge, hasMaxAge := requestControl["max-age"]
+				mustRevalidate := hasDirective(requestControl, "no-cache") || (hasMaxAge && maxAge == "0")
+				if !mustRevalidate && c.now().Before(entry.Expires) {
+					return entry.response(req, c.now()), nil
+				}
+				if etag := entry.Header.Get("ETag"); etag != "" {
+					req.Header.Set("If-None-Match", etag)
+				}
+				if lastModified := entry.Header.Get("Last-Modified"); lastModified != "" {
+					req.Header.Set("If-Modified-Since", lastModified)
+				}
+			}
+
+			resp, err := next(req)
+			if err != nil {
+				return nil, err
+			}
+			if entry != nil && resp.StatusCode == http.StatusNotModified {
+				drainBody(resp.Body)
+				entry.refresh(resp.Header, c.now())
+				c.save(ctx, key, entry)
+				return entry.response(req, c.now()), nil
+			}
+			if entry != nil {
+				c.store.Delete(ctx, key)
+			}
+			return c.storeResponse(ctx, key, req, resp), nil
+		}
+	}
+}
+
+// load returns the cached entry of a request, nil on a miss.
+func (c *HttpCache) load(ctx context.Context, key string, req *http.Request) *cachedResponse {
+	data, ok, err := c.store.Get(ctx, key)
+	if err != nil || !ok {
+		return nil
+	}
+	var entry cachedResponse
+	if json.Unmarshal(data, &entry) != nil {
+		return nil
+	}
+	for name, values := range entry.Vary {
+		if strings.Join(req.Header.Values(name), ",") != strings.Join(values, ",") {
+			return nil
+		}
+	}
+	return &entry
+}
+
+// storeResponse caches a response when it is cacheable and returns it with a readable body.
+func (c *HttpCache) storeResponse(ctx context.Context, key string, req *http.Request, resp *http.Response) *http.Response {
+	control := parseCacheControl(resp.Header)
+	if hasDirective(control, "no-store") || resp.Header.Get("Vary") == "*" ||
+		(resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNonAuthoritativeInfo) ||
+		resp.ContentLength > HTTP_CACHE_MAX_BODY_SIZE {
+		return resp
+	}
+	now := c.now()
+	entry := &cachedResponse{StatusCode: resp.StatusCode, Status: resp.Status, StoredAt: now}
+	entry.refresh(resp.Header, now)
+	if !now.Before(entry.Expires) && entry.Header.Get("ETag") == "" && entry.Header.Get("Last-Modified") == "" {
+		return resp
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, HTTP_CACHE_MAX_BODY_SIZE+1))
+	if err != nil || int64(len(body)) > HTTP_CACHE_MAX_BODY_SIZE {
+		// hand the partially read body back to the caller
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return resp
+	}
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	entry.Body = body
+	for _, name := range resp.Header.Values("Vary") {
+		for _, field := range strings.Split(name, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				if entry.Vary == nil {
+					entry.Vary = make(http.Header)
+				}
+				entry.Vary[http.CanonicalHeaderKey(field)] = req.Header.Values(field)
+			}
+		}
+	}
+	c.save(ctx, key, entry)
+	return resp
+}
+
+// save writes an entry to the store. Entries with validators outlive their
+// freshness lifetime so that they can be revalidated.
+func (c *HttpCache) save(ctx context.Context, key string, entry *cachedResponse) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	ttl := entry.Expires.Sub(c.now())
+	if entry.Header.Get("ETag") != "" || entry.Header.Get("Last-Modified") != "" {
+		ttl += HTTP_CACHE_VALIDATOR_TTL
+	}
+	if ttl > 0 {
+		c.store.Set(ctx, key, data, ttl)
+	}
+}
+
+// refresh updates the entry headers and freshness lifetime from a response received at now.
+func (e *cachedResponse) refresh(header http.Header, now time.Time) {
+	if e.Header == nil {
+		e.Header = header.Clone()
+	} else {
+		// a 304 response updates the stored headers
+		for name, values := range header {
+			if name != "Content-Length" {
+				e.Header[name] = values
+			}
+		}
+	}
+	e.StoredAt = now
+	e.Expires = now
+
+	control := parseCacheControl(e.Header)
+	if hasDirective(control, "no-cache") {
+		return
+	}
+	age, _ := strconv.Atoi(e.Header.Get("Age"))
+	if value, ok := control["max-age"]; ok {
+		if maxAge, err := strconv.Atoi(value); err == nil {
+			e.Expires = now.Add(time.Duration(maxAge-age) * time.Second)
+		}
+		return
+	}
+	if expires, err := http.ParseTime(e.Header.Get("Expires")); err == nil {
+		date, err := http.ParseTime(e.Header.Get("Date"))
+		if err != nil {
+			date = now
+		}
+		e.Expires = now.Add(expires.Sub(date))
+	}
+}
+
+// response builds the response served from the entry.
+func (e *cachedResponse) response(req *http.Request, now time.Time) *http.Response {
+	header := e.Header.Clone()
+	header.Set("Age", strconv.Itoa(int(now.Sub(e.StoredAt).Seconds())))
+	return &http.Response{
+		Status:        e.Status,
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// Get implements CacheStore.
+func (s *lruCacheStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	element, ok := s.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+	item := element.Value.(*lruCacheItem)
+	if time.Now().After(item.expires) {
+		s.order.Remove(element)
+		delete(s.items, key)
+		return nil, false, nil
+	}
+	s.order.MoveToFront(element)
+	return item.value, true, nil
+}
+
+// Set implements CacheStore.
+func (s *lruCacheStore) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item := &lruCacheItem{key: key, value: value, expires: time.Now().Add(ttl)}
+	if element, ok := s.items[key]; ok {
+		element.Value = item
+		s.order.MoveToFront(element)
+		return nil
+	}
+	s.items[key] = s.order.PushFront(item)
+	for s.order.Len() > s.capacity {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.items, oldest.Value.(*lruCacheItem).key)
+	}
+	return nil
+}
+
+// Delete implements CacheStore.
+func (s *lruCacheStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if element, ok := s.items[key]; ok {
+		s.order.Remove(element)
+		delete(s.items, key)
+	}
+	return nil
+}
+
+// cacheKey returns the store key of a request.
+func cacheKey(req *http.Request) string {
+	hash := sha256.Sum256([]byte(req.Method + " " + req.URL.String()))
+	return hex.EncodeToString(hash[:])
+}
+
+// parseCacheControl parses the Cache-Control header into lower-cased directives.
+func parseCacheControl(header http.Header) map[string]string {
+	directives := make(map[string]string)
+	for _, value := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			name, argument, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if name != "" {
+				directives[strings.ToLower(name)] = strings.Trim(argument, `"`)
+			}
+		}
+	}
+	return directives
+}
+
+// hasDirective reports whether a Cache-Control directive is present.
+func hasDirective(directives map[string]string, name string) bool {
+	_, ok := directives[name]
+	return ok
+}
@@ -0,0 +1,124 @@
+package utilsx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHttpCacheMaxAge(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if r.URL.Path == "/private" {
+			w.Header().Set("Cache-Control", "no-store")
+		} else {
+			w.Header().Set("Cache-Control", "public, max-age=60")
+		}
+		w.Write([]byte("regions"))
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	cache := NewHttpCache(nil)
+	cache.now = func() time.Time { return now }
+	client := NewHttpClient(WithCache(cache))
+
+	// 新鲜期内直接返回缓存，不请求服务端
+	for i := 0; i < 3; i++ {
+		resp, err := client.NewRequest(server.URL).SetUri("regions").Do(HTTP_METHOD_GET)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result, _ := resp.Result(); string(result) != "regions" {
+			t.Fatalf("unexpected response %s", result)
+		}
+	}
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Fatalf("expected 1 call, got %d", calls)
+	}
+
+	// 过期后重新请求
+	now = now.Add(61 * time.Second)
+	client.NewRequest(server.URL).SetUri("regions").Do(HTTP_METHOD_GET)
+	if atomic.LoadInt32(&calls) != 2 {
+		t.Fatalf("expected expired entry to be refetched, got %d calls", calls)
+	}
+
+	// no-store响应和非GET请求不缓存
+	client.NewRequest(server.URL).SetUri("private").Do(HTTP_METHOD_GET)
+	client.NewRequest(server.URL).SetUri("private").Do(HTTP_METHOD_GET)
+	client.NewRequest(server.URL).SetUri("regions").Do(HTTP_METHOD_POST)
+	if atomic.LoadInt32(&calls) != 5 {
+		t.Fatalf("expected uncached calls, got %d", calls)
+	}
+
+	// 请求携带no-cache时强制请求服务端
+	client.NewRequest(server.URL).SetUri("regions").SetHeader("Cache-Control", "no-cache").Do(HTTP_METHOD_GET)
+	if atomic.LoadInt32(&calls) != 6 {
+		t.Fatalf("expected no-cache request to reach the server, got %d calls", calls)
+	}
+}
+
+func TestHttpCacheRevalidation(t *testing.T) {
+	var calls, notModified int32
+	lastModified := time.Now().UTC().Format(http.TimeFormat)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", lastModified)
+		if r.Header.Get("If-None-Match") == `"v1"` && r.Header.Get("If-Modified-Since") == lastModified {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte("dictionary"))
+	}))
+	defer server.Close()
+
+	// 过期的缓存使用ETag重新验证，304时返回缓存内容
+	client := NewHttpClient(WithCache(NewHttpCache(NewLRUCacheStore(8))))
+	for i := 0; i < 3; i++ {
+		resp, err := client.NewRequest(server.URL).Do(HTTP_METHOD_GET)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result, _ := resp.Result(); resp.StatusCode() != http.StatusOK || string(result) != "dictionary" {
+			t.Fatalf("unexpected response %d %s", resp.StatusCode(), result)
+		}
+	}
+	if atomic.LoadInt32(&calls) != 3 || atomic.LoadInt32(&notModified) != 2 {
+		t.Fatalf("expected 2 revalidations out of 3 calls, got %d of %d", notModified, calls)
+	}
+}
+
+func TestLRUCacheStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewLRUCacheStore(2)
+	store.Set(ctx, "a", []byte("a"), time.Minute)
+	store.Set(ctx, "b", []byte("b"), time.Minute)
+	store.Get(ctx, "a")
+	store.Set(ctx, "c", []byte("c"), time.Minute)
+
+	// 超出容量时淘汰最久未使用的条目
+	if _, ok, _ := store.Get(ctx, "b"); ok {
+		t.Fatal("expected least recently used entry to be evicted")
+	}
+	if value, ok, _ := store.Get(ctx, "a"); !ok || string(value) != "a" {
+		t.Fatal("expected recently used entry to be kept")
+	}
+
+	// 过期条目不再返回
+	store.Set(ctx, "d", []byte("d"), -time.Second)
+	if _, ok, _ := store.Get(ctx, "d"); ok {
+		t.Fatal("expected expired entry to be dropped")
+	}
+	store.Delete(ctx, "a")
+	if _, ok, _ := store.Get(ctx, "a"); ok {
+		t.Fatal("expected deleted entry to be dropped")
+	}
+}
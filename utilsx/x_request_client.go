@@ -0,0 +1,191 @@
+package utilsx
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	HTTP_CLIENT_DEFAULT_TIMEOUT                 time.Duration = 5 * time.Second
+	HTTP_CLIENT_DEFAULT_DIAL_TIMEOUT            time.Duration = 5 * time.Second
+	HTTP_CLIENT_DEFAULT_KEEP_ALIVE              time.Duration = 30 * time.Second
+	HTTP_CLIENT_DEFAULT_TLS_HANDSHAKE_TIMEOUT   time.Duration = 5 * time.Second
+	HTTP_CLIENT_DEFAULT_IDLE_CONN_TIMEOUT       time.Duration = 90 * time.Second
+	HTTP_CLIENT_DEFAULT_MAX_IDLE_CONNS          int           = 256
+	HTTP_CLIENT_DEFAULT_MAX_IDLE_CONNS_PER_HOST int           = 32
+)
+
+// HttpClient is a reusable, concurrency-safe HTTP client that ApiRequests are created from.
+//
+// Every HttpClient owns its own http.Transport, so its connection pool and
+// timeouts never affect http.DefaultClient or other HttpClients.
+type HttpClient struct {
+	dialer       *net.Dialer
+	transport    *http.Transport
+	roundTripper http.RoundTripper // overrides transport when set
+	httpClient   *http.Client
+
+	timeout     time.Duration // default timeout of the requests created from this client
+	middlewares []Middleware  // middlewares run for every request of this client
+	compression string        // default request body content encoding
+
+	idempotencyHeader string // header of the automatic idempotency keys, empty disables them
+}
+
+type HttpClientOption func(*HttpClient)
+
+var (
+	defaultHttpClient     atomic.Pointer[HttpClient]
+	defaultHttpClientOnce sync.Once
+)
+
+// NewHttpClient creates a new HttpClient.
+//
+// It takes a variadic list of HttpClientOption to tune the client and its transport.
+// It returns a pointer to the created HttpClient.
+func NewHttpClient(opts ...HttpClientOption) *HttpClient {
+	c := &HttpClient{
+		dialer: &net.Dialer{
+			Timeout:   HTTP_CLIENT_DEFAULT_DIAL_TIMEOUT,
+			KeepAlive: HTTP_CLIENT_DEFAULT_KEEP_ALIVE,
+		},
+		timeout: HTTP_CLIENT_DEFAULT_TIMEOUT,
+	}
+	c.transport = &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           c.dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          HTTP_CLIENT_DEFAULT_MAX_IDLE_CONNS,
+		MaxIdleConnsPerHost:   HTTP_CLIENT_DEFAULT_MAX_IDLE_CONNS_PER_HOST,
+		IdleConnTimeout:       HTTP_CLIENT_DEFAULT_IDLE_CONN_TIMEOUT,
+		TLSHandshakeTimeout:   HTTP_CLIENT_DEFAULT_TLS_HANDSHAKE_TIMEOUT,
+		ExpectContinueTimeout: time.Second,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	var roundTripper http.RoundTripper = c.transport
+	if c.roundTripper != nil {
+		roundTripper = c.roundTripper
+	}
+	// timeouts are applied per request through the request context,
+	// so the shared http.Client never carries one
+	c.httpClient = &http.Client{Transport: roundTripper}
+	return c
+}
+
+// DefaultHttpClient returns the shared HttpClient used by NewHttpRequest.
+//
+// The default client logs every request with LoggingMiddleware.
+//
+// No parameters.
+// Returns a pointer to the default HttpClient instance.
+func DefaultHttpClient() *HttpClient {
+	defaultHttpClientOnce.Do(func() {
+		defaultHttpClient.CompareAndSwap(nil, NewHttpClient(WithMiddleware(LoggingMiddleware())))
+	})
+	return defaultHttpClient.Load()
+}
+
+// SetDefaultHttpClient replaces the HttpClient used by NewHttpRequest, for
+// instance with a client replaying a Recorder cassette in tests.
+//
+// It takes the new default client.
+// It returns the previous default client, so that it can be restored.
+func SetDefaultHttpClient(client *HttpClient) *HttpClient {
+	previous := DefaultHttpClient()
+	defaultHttpClient.Store(client)
+	return previous
+}
+
+// NewRequest creates a new HTTP request sent through this client.
+//
+// It takes a string parameter `address` which represents the URL of the request.
+// The function returns an `executableApiRequest` object.
+func (c *HttpClient) NewRequest(address string) ExecutableApiRequest {
+	return newApiRequest(c, address)
+}
+
+// CloseIdleConnections closes the idle connections kept by the client transport.
+func (c *HttpClient) CloseIdleConnections() {
+	c.httpClient.CloseIdleConnections()
+}
+
+// WithTimeout sets the default timeout of the requests created from the client.
+//
+// Requests can still override it with SetTimeout.
+func WithTimeout(timeout time.Duration) HttpClientOption {
+	return func(c *HttpClient) {
+		c.timeout = timeout
+	}
+}
+
+// WithDialTimeout sets the maximum amount of time a dial waits for a connect to complete.
+func WithDialTimeout(timeout time.Duration) HttpClientOption {
+	return func(c *HttpClient) {
+		c.dialer.Timeout = timeout
+	}
+}
+
+// WithKeepAlive sets the interval between keep-alive probes of active connections.
+func WithKeepAlive(keepAlive time.Duration) HttpClientOption {
+	return func(c *HttpClient) {
+		c.dialer.KeepAlive = keepAlive
+	}
+}
+
+// WithTLSHandshakeTimeout sets the maximum amount of time to wait for a TLS handshake.
+func WithTLSHandshakeTimeout(timeout time.Duration) HttpClientOption {
+	return func(c *HttpClient) {
+		c.transport.TLSHandshakeTimeout = timeout
+	}
+}
+
+// WithResponseHeaderTimeout sets the maximum amount of time to wait for the
+// response headers after the request has been written.
+func WithResponseHeaderTimeout(timeout time.Duration) HttpClientOption {
+	return func(c *HttpClient) {
+		c.transport.ResponseHeaderTimeout = timeout
+	}
+}
+
+// WithIdleConnTimeout sets how long an idle connection stays in the pool.
+func WithIdleConnTimeout(timeout time.Duration) HttpClientOption {
+	return func(c *HttpClient) {
+		c.transport.IdleConnTimeout = timeout
+	}
+}
+
+// WithMaxIdleConns sets the maximum number of idle connections across all hosts.
+func WithMaxIdleConns(n int) HttpClientOption {
+	return func(c *HttpClient) {
+		c.transport.MaxIdleConns = n
+	}
+}
+
+// WithMaxIdleConnsPerHost sets the maximum number of idle connections kept per host.
+func WithMaxIdleConnsPerHost(n int) HttpClientOption {
+	return func(c *HttpClient) {
+		c.transport.MaxIdleConnsPerHost = n
+	}
+}
+
+// WithMaxConnsPerHost limits the total number of connections per host, 0 means no limit.
+func WithMaxConnsPerHost(n int) HttpClientOption {
+	return func(c *HttpClient) {
+		c.transport.MaxConnsPerHost = n
+	}
+}
+
+// WithTransport replaces the client transport with a custom http.RoundTripper.
+//
+// The transport tuning options have no effect once a custom RoundTripper is set.
+func WithTransport(roundTripper http.RoundTripper) HttpClientOption {
+	return func(c *HttpClient) {
+		c.roundTripper = roundTripper
+	}
+}
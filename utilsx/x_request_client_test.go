@@ -0,0 +1,51 @@
+package utilsx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHttpClientConcurrent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("slow") != "" {
+			time.Sleep(100 * time.Millisecond)
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	client := NewHttpClient(
+		WithTimeout(time.Second),
+		WithMaxIdleConnsPerHost(8),
+		WithResponseHeaderTimeout(time.Second),
+	)
+	defer client.CloseIdleConnections()
+
+	// 不同超时时间的请求并发执行，互不影响
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req := client.NewRequest(server.URL).SetQueryParam("slow", "1")
+			if i%2 == 0 {
+				req.SetTimeout(10 * time.Millisecond)
+				if _, err := req.Do(HTTP_METHOD_GET); err == nil {
+					t.Error("expected timeout error")
+				}
+				return
+			}
+			if _, err := req.Do(HTTP_METHOD_GET); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if http.DefaultClient.Timeout != 0 {
+		t.Fatal("http.DefaultClient must not be modified")
+	}
+}
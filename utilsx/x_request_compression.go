@@ -0,0 +1,226 @@
+package utilsx
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	CONTENT_ENCODING_GZIP    string = "gzip"
+	CONTENT_ENCODING_DEFLATE string = "deflate"
+	CONTENT_ENCODING_ZSTD    string = "zstd"
+)
+
+// compressReader compresses a stream as it is read, without buffering it whole.
+type compressReader struct {
+	source     io.Reader
+	compressed bytes.Buffer
+	writer     io.WriteCloser
+	chunk      []byte
+	eof        bool
+}
+
+// decodedBody decompresses a response body on its first read, so that empty
+// bodies never fail on a missing compression header.
+type decodedBody struct {
+	body      io.ReadCloser
+	encodings []string
+	reader    io.Reader
+	closers   []func()
+	err       error
+}
+
+// WithCompression compresses the body of every request of the client.
+//
+// It takes CONTENT_ENCODING_GZIP, CONTENT_ENCODING_DEFLATE or CONTENT_ENCODING_ZSTD.
+// Requests can still override it with SetCompression.
+func WithCompression(encoding string) HttpClientOption {
+	return func(c *HttpClient) {
+		c.compression = encoding
+	}
+}
+
+// SetCompression compresses the body of the apiRequest and sets its Content-Encoding header.
+//
+// Parameters:
+//   - encoding: CONTENT_ENCODING_GZIP, CONTENT_ENCODING_DEFLATE or
+//     CONTENT_ENCODING_ZSTD, an empty string sends the body uncompressed.
+//
+// Returns:
+//   - executableApiRequest: The modified apiRequest struct.
+func (r *ApiRequest) SetCompression(encoding string) ExecutableApiRequest {
+	r.compression = encoding
+	return r
+}
+
+// compress returns the body compressed with the encoding.
+//
+// Buffered bodies are compressed once, streamed bodies while they are sent.
+func (b *requestBody) compress(encoding string) (*requestBody, error) {
+	if b == nil || encoding == "" {
+		return b, nil
+	}
+	compressed := &requestBody{contentType: b.contentType, contentEncoding: encoding}
+	if b.stream != nil {
+		writer := &compressReader{source: b.stream, chunk: make([]byte, 32*1024)}
+		var err error
+		if writer.writer, err = newCompressWriter(&writer.compressed, encoding); err != nil {
+			return nil, err
+		}
+		compressed.stream = writer
+		return compressed, nil
+	}
+
+	var buffer bytes.Buffer
+	writer, err := newCompressWriter(&buffer, encoding)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = writer.Write(b.data); err != nil {
+		return nil, err
+	}
+	if err = writer.Close(); err != nil {
+		return nil, err
+	}
+	compressed.data = buffer.Bytes()
+	return compressed, nil
+}
+
+// newCompressWriter returns a writer compressing into w.
+func newCompressWriter(w io.Writer, encoding string) (io.WriteCloser, error) {
+	switch strings.ToLower(encoding) {
+	case CONTENT_ENCODING_GZIP:
+		return gzip.NewWriter(w), nil
+	case CONTENT_ENCODING_DEFLATE:
+		// the HTTP deflate coding is the zlib format (RFC 9110)
+		return zlib.NewWriter(w), nil
+	case CONTENT_ENCODING_ZSTD:
+		return zstd.NewWriter(w)
+	}
+	return nil, fmt.Errorf("utilsx: unsupported content encoding %q", encoding)
+}
+
+// Read implements io.Reader.
+func (c *compressReader) Read(p []byte) (int, error) {
+	for c.compressed.Len() == 0 && !c.eof {
+		n, err := c.source.Read(c.chunk)
+		if n > 0 {
+			if _, writeErr := c.writer.Write(c.chunk[:n]); writeErr != nil {
+				return 0, writeErr
+			}
+		}
+		if err == io.EOF {
+			c.eof = true
+			if err = c.writer.Close(); err != nil {
+				return 0, err
+			}
+		} else if err != nil {
+			return 0, err
+		}
+	}
+	if c.compressed.Len() == 0 {
+		return 0, io.EOF
+	}
+	return c.compressed.Read(p)
+}
+
+// decompressResponse decodes the gzip, deflate and zstd response bodies left
+// compressed by the transport, which happens when the caller sets its own
+// Accept-Encoding header.
+func decompressResponse(next RoundTripFunc) RoundTripFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		resp, err := next(req)
+		if err != nil || resp.Uncompressed || req.Method == http.MethodHead ||
+			resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotModified {
+			return resp, err
+		}
+		var encodings []string
+		for _, value := range resp.Header.Values("Content-Encoding") {
+			for _, encoding := range strings.Split(value, ",") {
+				encoding = strings.ToLower(strings.TrimSpace(encoding))
+				switch encoding {
+				case "", "identity":
+				case CONTENT_ENCODING_GZIP, "x-gzip", CONTENT_ENCODING_DEFLATE, CONTENT_ENCODING_ZSTD:
+					encodings = append(encodings, encoding)
+				default:
+					// an unknown coding is left for the caller
+					return resp, nil
+				}
+			}
+		}
+		if len(encodings) == 0 {
+			return resp, nil
+		}
+		resp.Body = &decodedBody{body: resp.Body, encodings: encodings}
+		resp.Header.Del("Content-Encoding")
+		resp.Header.Del("Content-Length")
+		resp.ContentLength = -1
+		resp.Uncompressed = true
+		return resp, nil
+	}
+}
+
+// Read implements io.Reader.
+func (d *decodedBody) Read(p []byte) (int, error) {
+	if d.reader == nil && d.err == nil {
+		d.reader, d.err = d.open()
+	}
+	if d.err != nil {
+		return 0, d.err
+	}
+	return d.reader.Read(p)
+}
+
+// Close implements io.Closer.
+func (d *decodedBody) Close() error {
+	for _, closer := range d.closers {
+		closer()
+	}
+	return d.body.Close()
+}
+
+// open chains the decoders, the last applied coding first.
+func (d *decodedBody) open() (io.Reader, error) {
+	var reader io.Reader = d.body
+	for i := len(d.encodings) - 1; i >= 0; i-- {
+		switch d.encodings[i] {
+		case CONTENT_ENCODING_GZIP, "x-gzip":
+			gzipReader, err := gzip.NewReader(reader)
+			if err != nil {
+				return nil, err
+			}
+			reader = gzipReader
+		case CONTENT_ENCODING_DEFLATE:
+			// some servers send raw deflate data instead of the zlib format
+			buffered := bufio.NewReader(reader)
+			header, err := buffered.Peek(2)
+			if err != nil {
+				return nil, err
+			}
+			if header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+				if reader, err = zlib.NewReader(buffered); err != nil {
+					return nil, err
+				}
+			} else {
+				reader = flate.NewReader(buffered)
+			}
+		case CONTENT_ENCODING_ZSTD:
+			decoder, err := zstd.NewReader(reader)
+			if err != nil {
+				return nil, err
+			}
+			d.closers = append(d.closers, decoder.Close)
+			reader = decoder
+		}
+	}
+	return reader, nil
+}
@@ -0,0 +1,111 @@
+package utilsx
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// decodeTestBody 按Content-Encoding解压请求体
+func decodeTestBody(t *testing.T, r *http.Request) string {
+	resp := &http.Response{Header: http.Header{"Content-Encoding": {r.Header.Get("Content-Encoding")}}, Body: r.Body, StatusCode: http.StatusOK}
+	decoded, _ := decompressResponse(func(*http.Request) (*http.Response, error) { return resp, nil })(r)
+	data, err := io.ReadAll(decoded.Body)
+	if err != nil {
+		t.Error(err)
+	}
+	return string(data)
+}
+
+func TestRequestCompression(t *testing.T) {
+	payload := strings.Repeat(`{"event":"page_view"},`, 200)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Content-Encoding") + ":" + decodeTestBody(t, r)))
+	}))
+	defer server.Close()
+
+	// 三种压缩方式的请求体都能被正确解压
+	for _, encoding := range []string{CONTENT_ENCODING_GZIP, CONTENT_ENCODING_DEFLATE, CONTENT_ENCODING_ZSTD} {
+		resp, err := NewHttpClient().NewRequest(server.URL).SetRawBody([]byte(payload), CONTENT_TYPE_JSON).
+			SetCompression(encoding).Do(HTTP_METHOD_POST)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result, _ := resp.Result(); string(result) != encoding+":"+payload {
+			t.Fatalf("%s: unexpected body %.40s", encoding, result)
+		}
+	}
+
+	// 流式请求体边读边压缩，客户端默认压缩可被请求覆盖
+	client := NewHttpClient(WithCompression(CONTENT_ENCODING_GZIP))
+	resp, err := client.NewRequest(server.URL).SetBodyReader(strings.NewReader(payload), CONTENT_TYPE_JSON).Do(HTTP_METHOD_POST)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result, _ := resp.Result(); string(result) != "gzip:"+payload {
+		t.Fatalf("unexpected streamed body %.40s", result)
+	}
+	resp, err = client.NewRequest(server.URL).SetRawBody([]byte("plain"), "").SetCompression("").Do(HTTP_METHOD_POST)
+	if result, _ := resp.Result(); err != nil || string(result) != ":plain" {
+		t.Fatalf("expected uncompressed body, got %s %v", result, err)
+	}
+
+	if _, err = client.NewRequest(server.URL).SetCompression("br").Do(HTTP_METHOD_POST); err == nil {
+		t.Fatal("expected unsupported encoding error")
+	}
+}
+
+func TestResponseDecompression(t *testing.T) {
+	payload := strings.Repeat("region,", 100)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var buffer bytes.Buffer
+		var writer io.WriteCloser
+		encoding := r.URL.Query().Get("encoding")
+		switch encoding {
+		case "gzip":
+			writer = gzip.NewWriter(&buffer)
+		case "deflate":
+			// 原始deflate格式，非zlib
+			writer, _ = flate.NewWriter(&buffer, flate.DefaultCompression)
+		case "zstd":
+			writer, _ = zstd.NewWriter(&buffer)
+		}
+		if writer == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writer.Write([]byte(payload))
+		writer.Close()
+		w.Header().Set("Content-Encoding", encoding)
+		w.Write(buffer.Bytes())
+	}))
+	defer server.Close()
+
+	// 调用方自行设置Accept-Encoding时也能自动解压响应
+	for _, encoding := range []string{"gzip", "deflate", "zstd"} {
+		resp, err := NewHttpClient().NewRequest(server.URL).SetQueryParam("encoding", encoding).
+			SetHeader("Accept-Encoding", "gzip, deflate, zstd").Do(HTTP_METHOD_GET)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result, _ := resp.Result(); string(result) != payload {
+			t.Fatalf("%s: unexpected body %.40q", encoding, result)
+		}
+		if resp.Header().Get("Content-Encoding") != "" {
+			t.Fatalf("%s: Content-Encoding should be removed", encoding)
+		}
+	}
+
+	// 空响应不解压
+	resp, err := NewHttpClient().NewRequest(server.URL).SetHeader("Accept-Encoding", "gzip").Do(HTTP_METHOD_GET)
+	if err != nil || resp.StatusCode() != http.StatusNoContent {
+		t.Fatalf("unexpected empty response %d %v", resp.StatusCode(), err)
+	}
+}
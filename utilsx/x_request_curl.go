@@ -0,0 +1,508 @@
+package utilsx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type curlOptions struct {
+	redactHeaders []string
+	redactQuery   []string
+	applyAuth     bool
+}
+
+type CurlOption func(*curlOptions)
+
+// curlIgnoredFlags are the curl options without argument that do not change the request.
+var curlIgnoredFlags = map[string]bool{
+	"-s": true, "--silent": true, "-S": true, "--show-error": true, "-v": true, "--verbose": true,
+	"-i": true, "--include": true, "-L": true, "--location": true, "-k": true, "--insecure": true,
+	"-f": true, "--fail": true, "--compressed": true, "-g": true, "--globoff": true,
+}
+
+// WithCurlRedaction redacts the headers also redacted by LoggingMiddleware, such as Authorization.
+func WithCurlRedaction() CurlOption {
+	return func(o *curlOptions) {
+		o.redactHeaders = defaultRedactHeaders
+	}
+}
+
+// WithCurlRedactHeaders redacts the values of the given headers.
+func WithCurlRedactHeaders(headers ...string) CurlOption {
+	return func(o *curlOptions) {
+		o.redactHeaders = headers
+	}
+}
+
+// WithCurlRedactQueryParams redacts the values of the given query parameters, such as API keys.
+func WithCurlRedactQueryParams(params ...string) CurlOption {
+	return func(o *curlOptions) {
+		o.redactQuery = params
+	}
+}
+
+// WithCurlAuth applies the authentication provider of the apiRequest to the
+// command. Providers such as OAuth2ClientCredentials may fetch a token to do so.
+func WithCurlAuth() CurlOption {
+	return func(o *curlOptions) {
+		o.applyAuth = true
+	}
+}
+
+// Curl renders the apiRequest as a curl command.
+//
+// The body is rendered uncompressed. Headers added at send time by middlewares
+// are not included, nor is the authentication unless WithCurlAuth is used.
+// The apiRequest is left unchanged: the content of its multipart files is
+// kept for the next Do call.
+//
+// Parameters:
+//   - method: the HTTP method of the request.
+//   - opts: the redaction options.
+//
+// Returns:
+//   - string: the curl command.
+//   - error: the body encoding or authentication error.
+func (r *ApiRequest) Curl(method HttpMethod, opts ...CurlOption) (string, error) {
+	options := curlOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	body, err := r.encodeBody(method)
+	if err != nil {
+		return "", err
+	}
+	req, err := r.newRequest(context.Background(), method, body)
+	if err != nil {
+		return "", err
+	}
+	if body != nil && body.stream == nil {
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body.data)), nil
+		}
+	}
+	if options.applyAuth && r.auth != nil {
+		if err = r.auth.Apply(req); err != nil {
+			return "", err
+		}
+	}
+	return CurlCommand(req, opts...)
+}
+
+// CurlCommand renders an outgoing request as a curl command. It can be used in
+// a Middleware to capture the exact requests sent, including their authentication.
+//
+// Parameters:
+//   - req: the request to render, its body is read through GetBody.
+//   - opts: the redaction options.
+//
+// Returns:
+//   - string: the curl command.
+//   - error: the body reading error.
+func CurlCommand(req *http.Request, opts ...CurlOption) (string, error) {
+	options := curlOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	var body []byte
+	hasBody := req.Body != nil && req.Body != http.NoBody
+	if hasBody && req.GetBody != nil {
+		reader, err := req.GetBody()
+		if err != nil {
+			return "", err
+		}
+		defer reader.Close()
+		if body, err = io.ReadAll(reader); err != nil {
+			return "", err
+		}
+	}
+
+	requestUrl := *req.URL
+	if len(options.redactQuery) > 0 {
+		query := requestUrl.Query()
+		for _, name := range options.redactQuery {
+			if query.Has(name) {
+				query.Set(name, LOG_REDACTED)
+			}
+		}
+		requestUrl.RawQuery = query.Encode()
+	}
+
+	command := "curl "
+	if req.Method != http.MethodGet || hasBody {
+		command += "-X " + req.Method + " "
+	}
+	parts := []string{command + shellQuote(requestUrl.String())}
+
+	redacted := make(map[string]bool)
+	for _, name := range options.redactHeaders {
+		redacted[http.CanonicalHeaderKey(name)] = true
+	}
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range req.Header[name] {
+			if redacted[http.CanonicalHeaderKey(name)] {
+				value = LOG_REDACTED
+			}
+			parts = append(parts, "-H "+shellQuote(name+": "+value))
+		}
+	}
+
+	switch {
+	case hasBody && req.GetBody == nil:
+		// a streamed body is read from the standard input
+		parts = append(parts, "--data-binary @-")
+	case hasBody:
+		parts = append(parts, "--data-raw "+shellQuote(string(body)))
+	}
+	return strings.Join(parts, " \\\n  "), nil
+}
+
+// ParseCurl builds a request sent through the DefaultHttpClient from a curl command.
+//
+// It is a shorthand for the ParseCurl method of DefaultHttpClient().
+func ParseCurl(command string) (ExecutableApiRequest, HttpMethod, error) {
+	return DefaultHttpClient().ParseCurl(command)
+}
+
+// ParseCurl builds a request sent through this client from a curl command.
+//
+// The URL, -X, -H, -d, --data-raw, --data-binary, --data-urlencode, --json,
+// -F, -G, -I, -u, -A, -e and -b options are supported. Options that do not
+// change the request, such as -s or -L, are ignored, other options are an error.
+//
+// Parameters:
+//   - command: the curl command line, with shell quoting and line continuations.
+//
+// Returns:
+//   - ExecutableApiRequest: the parsed request.
+//   - HttpMethod: the method of the request.
+//   - error: the parsing error.
+func (c *HttpClient) ParseCurl(command string) (ExecutableApiRequest, HttpMethod, error) {
+	args, err := shellSplit(command)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(args) > 0 && args[0] == "curl" {
+		args = args[1:]
+	}
+
+	var (
+		address, method string
+		headers         [][2]string
+		data            []string
+		form            [][2]string
+		getData, head   bool
+		jsonBody        bool
+		user            string
+	)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			address = arg
+			continue
+		}
+		if curlIgnoredFlags[arg] {
+			continue
+		}
+		if ignoredShortFlags(arg) {
+			continue
+		}
+
+		name, value, attached := arg, "", false
+		if !strings.HasPrefix(arg, "--") && len(arg) > 2 {
+			name, value, attached = arg[:2], arg[2:], true
+		}
+		switch name {
+		case "-G", "--get":
+			getData = true
+			continue
+		case "-I", "--head":
+			head = true
+			continue
+		}
+		if !attached {
+			if i+1 >= len(args) {
+				return nil, "", fmt.Errorf("utilsx: curl option %s needs a value", name)
+			}
+			i++
+			value = args[i]
+		}
+		switch name {
+		case "--url":
+			address = value
+		case "-X", "--request":
+			method = strings.ToUpper(value)
+		case "-H", "--header":
+			key, headerValue, ok := strings.Cut(value, ":")
+			if !ok {
+				return nil, "", fmt.Errorf("utilsx: invalid curl header %q", value)
+			}
+			headers = append(headers, [2]string{strings.TrimSpace(key), strings.TrimSpace(headerValue)})
+		case "-A", "--user-agent":
+			headers = append(headers, [2]string{"User-Agent", value})
+		case "-e", "--referer":
+			headers = append(headers, [2]string{"Referer", value})
+		case "-b", "--cookie":
+			headers = append(headers, [2]string{"Cookie", value})
+		case "-u", "--user":
+			user = value
+		case "-d", "--data", "--data-ascii", "--data-binary":
+			if strings.HasPrefix(value, "@") {
+				content, err := os.ReadFile(value[1:])
+				if err != nil {
+					return nil, "", err
+				}
+				value = string(content)
+				if name != "--data-binary" {
+					value = strings.NewReplacer("\r", "", "\n", "").Replace(value)
+				}
+			}
+			data = append(data, value)
+		case "--data-raw":
+			data = append(data, value)
+		case "--json":
+			jsonBody = true
+			data = append(data, value)
+		case "--data-urlencode":
+			key, raw, ok := strings.Cut(value, "=")
+			if ok {
+				value = url.QueryEscape(raw)
+				if key != "" {
+					value = key + "=" + value
+				}
+			} else {
+				value = url.QueryEscape(value)
+			}
+			data = append(data, value)
+		case "-F", "--form":
+			key, formValue, ok := strings.Cut(value, "=")
+			if !ok {
+				return nil, "", fmt.Errorf("utilsx: invalid curl form field %q", value)
+			}
+			form = append(form, [2]string{key, formValue})
+		default:
+			return nil, "", fmt.Errorf("utilsx: unsupported curl option %s", name)
+		}
+	}
+	if address == "" {
+		return nil, "", errors.New("utilsx: curl command has no URL")
+	}
+
+	req := newApiRequest(c, address)
+	for _, header := range headers {
+		req.AddHeader(header[0], header[1])
+	}
+	if user != "" {
+		username, password, _ := strings.Cut(user, ":")
+		req.SetAuth(BasicAuth(username, password))
+	}
+
+	switch {
+	case len(form) > 0:
+		req.bodyMode = bodyModeMultipart
+		for _, field := range form {
+			value, _, _ := strings.Cut(field[1], ";")
+			if !strings.HasPrefix(value, "@") {
+				req.SetFormValue(field[0], strings.TrimPrefix(value, "\\"))
+				continue
+			}
+			content, err := os.ReadFile(value[1:])
+			if err != nil {
+				return nil, "", err
+			}
+			req.SetMultipartFile(field[0], filepath.Base(value[1:]), bytes.NewReader(content))
+		}
+	case len(data) > 0 && getData:
+		for _, pair := range data {
+			values, err := url.ParseQuery(pair)
+			if err != nil {
+				return nil, "", err
+			}
+			for key, list := range values {
+				for _, value := range list {
+					req.AddQueryParam(key, value)
+				}
+			}
+		}
+	case jsonBody:
+		req.SetRawBody([]byte(strings.Join(data, "")), CONTENT_TYPE_JSON)
+		if req.headers.Get("Accept") == "" {
+			req.SetHeader("Accept", CONTENT_TYPE_JSON)
+		}
+	case len(data) > 0:
+		req.SetRawBody([]byte(strings.Join(data, "&")), CONTENT_TYPE_FORM)
+	}
+
+	if method == "" {
+		switch {
+		case head:
+			method = http.MethodHead
+		case (len(data) > 0 && !getData) || len(form) > 0:
+			method = http.MethodPost
+		default:
+			method = http.MethodGet
+		}
+	}
+	if method == http.MethodGet || method == http.MethodHead {
+		req.SetForceBody(req.bodyMode != bodyModeJSONMap)
+	}
+	return req, HttpMethod(method), nil
+}
+
+// ignoredShortFlags reports whether arg combines ignored short options, such as -sSL.
+func ignoredShortFlags(arg string) bool {
+	if strings.HasPrefix(arg, "--") || len(arg) < 3 {
+		return false
+	}
+	for _, flag := range arg[1:] {
+		if !curlIgnoredFlags["-"+string(flag)] {
+			return false
+		}
+	}
+	return true
+}
+
+// shellQuote quotes s for a POSIX shell, with ANSI-C quoting for binary data.
+func shellQuote(s string) string {
+	binary := !utf8.ValidString(s)
+	for i := 0; i < len(s) && !binary; i++ {
+		binary = (s[i] < 0x20 && s[i] != '\n' && s[i] != '\t') || s[i] == 0x7f
+	}
+	if !binary {
+		return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+	}
+	var quoted strings.Builder
+	quoted.WriteString("$'")
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\'' || c == '\\':
+			quoted.WriteByte('\\')
+			quoted.WriteByte(c)
+		case c >= 0x20 && c < 0x7f:
+			quoted.WriteByte(c)
+		default:
+			fmt.Fprintf(&quoted, "\\x%02x", c)
+		}
+	}
+	quoted.WriteString("'")
+	return quoted.String()
+}
+
+// shellSplit splits a command line into arguments like a POSIX shell, with
+// single, double and ANSI-C quoting and backslash line continuations.
+func shellSplit(command string) ([]string, error) {
+	var (
+		args    []string
+		current strings.Builder
+		inArg   bool
+	)
+	for i := 0; i < len(command); i++ {
+		c := command[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		case c == '\\':
+			if i+1 < len(command) {
+				i++
+				if command[i] == '\n' {
+					continue
+				}
+				if command[i] == '\r' && i+1 < len(command) && command[i+1] == '\n' {
+					i++
+					continue
+				}
+				current.WriteByte(command[i])
+			}
+			inArg = true
+		case c == '\'':
+			end := strings.IndexByte(command[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("utilsx: unterminated single quote in curl command")
+			}
+			current.WriteString(command[i+1 : i+1+end])
+			i += end + 1
+			inArg = true
+		case c == '$' && i+1 < len(command) && command[i+1] == '\'':
+			end, err := ansiQuoted(command[i+2:], &current)
+			if err != nil {
+				return nil, err
+			}
+			i += end + 2
+			inArg = true
+		case c == '"':
+			i++
+			for ; i < len(command) && command[i] != '"'; i++ {
+				if command[i] == '\\' && i+1 < len(command) && strings.IndexByte("\"\\$`\n", command[i+1]) >= 0 {
+					i++
+					if command[i] == '\n' {
+						continue
+					}
+				}
+				current.WriteByte(command[i])
+			}
+			if i >= len(command) {
+				return nil, errors.New("utilsx: unterminated double quote in curl command")
+			}
+			inArg = true
+		default:
+			current.WriteByte(c)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// ansiQuoted decodes the content of a $'...' string into out.
+//
+// It returns the index of the closing quote in s.
+func ansiQuoted(s string, out *strings.Builder) (int, error) {
+	escapes := map[byte]byte{'n': '\n', 't': '\t', 'r': '\r', '\\': '\\', '\'': '\'', '"': '"', '0': 0}
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\'':
+			return i, nil
+		case c == '\\' && i+1 < len(s):
+			i++
+			if s[i] == 'x' && i+2 < len(s) {
+				value, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+				if err != nil {
+					return 0, fmt.Errorf("utilsx: invalid escape in curl command: %w", err)
+				}
+				out.WriteByte(byte(value))
+				i += 2
+			} else if escaped, ok := escapes[s[i]]; ok {
+				out.WriteByte(escaped)
+			} else {
+				out.WriteByte('\\')
+				out.WriteByte(s[i])
+			}
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return 0, errors.New("utilsx: unterminated $' quote in curl command")
+}
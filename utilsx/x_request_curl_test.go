@@ -0,0 +1,222 @@
+package utilsx
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newEchoServer 返回把请求方法、查询参数、请求头和请求体以JSON返回的测试服务
+func newEchoServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"query":  r.URL.Query(),
+			"type":   r.Header.Get("Content-Type"),
+			"auth":   r.Header.Get("Authorization"),
+			"trace":  r.Header.Get("X-Trace"),
+			"body":   string(body),
+		})
+	}))
+}
+
+func TestCurlRoundTrip(t *testing.T) {
+	server := newEchoServer()
+	defer server.Close()
+
+	cases := []struct {
+		name   string
+		method HttpMethod
+		req    func() ExecutableApiRequest
+	}{
+		{"get", HTTP_METHOD_GET, func() ExecutableApiRequest {
+			return NewHttpClient().NewRequest(server.URL).SetUri("regions").SetQueryParam("q", "a b&c").SetHeader("X-Trace", "it's")
+		}},
+		{"json", HTTP_METHOD_POST, func() ExecutableApiRequest {
+			return NewHttpClient().NewRequest(server.URL).SetJSONBody(map[string]string{"name": "O'Brien\nJr"})
+		}},
+		{"form", HTTP_METHOD_PUT, func() ExecutableApiRequest {
+			return NewHttpClient().NewRequest(server.URL).SetFormValue("a", "1").SetFormValue("b", "x y")
+		}},
+		{"binary", HTTP_METHOD_POST, func() ExecutableApiRequest {
+			return NewHttpClient().NewRequest(server.URL).SetRawBody([]byte{0x00, 0xff, '\'', 0x1b}, CONTENT_TYPE_OCTET)
+		}},
+		{"auth", HTTP_METHOD_DELETE, func() ExecutableApiRequest {
+			return NewHttpClient().NewRequest(server.URL).SetAuth(BearerAuth("token"))
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// 导出的curl命令解析后发出的请求与原请求一致
+			command, err := c.req().Curl(c.method, WithCurlAuth())
+			if err != nil {
+				t.Fatal(err)
+			}
+			parsed, method, err := ParseCurl(command)
+			if err != nil {
+				t.Fatalf("%v\n%s", err, command)
+			}
+			if method != c.method {
+				t.Fatalf("expected method %s, got %s", c.method, method)
+			}
+			expected, err := c.req().Do(c.method)
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual, err := parsed.Do(method)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, _ := expected.Result()
+			got, _ := actual.Result()
+			if string(want) != string(got) {
+				t.Fatalf("expected %s, got %s\n%s", want, got, command)
+			}
+		})
+	}
+}
+
+func TestCurlKeepsRequest(t *testing.T) {
+	server := newEchoServer()
+	defer server.Close()
+
+	// 导出curl命令不修改请求，之后的请求结果不受影响
+	req := NewHttpClient().NewRequest(server.URL).SetJSONBody(map[string]string{"name": "golix"})
+	if _, err := req.Do(HTTP_METHOD_PUT); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := req.Curl(HTTP_METHOD_DELETE); err != nil {
+		t.Fatal(err)
+	}
+	if method := req.(*ApiRequest).method; method != string(HTTP_METHOD_PUT) {
+		t.Fatalf("expected method of the last send, got %s", method)
+	}
+
+	// 导出multipart请求后，上传的文件内容不为空
+	uploads := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		io.Copy(w, file)
+	}))
+	defer uploads.Close()
+	req = NewHttpClient().NewRequest(uploads.URL).SetMultipartFile("file", "a.txt", strings.NewReader("file content"))
+	if command, err := req.Curl(HTTP_METHOD_POST); err != nil || !strings.Contains(command, "file content") {
+		t.Fatalf("unexpected curl command %q %v", command, err)
+	}
+	for i := 0; i < 2; i++ {
+		resp, err := req.Do(HTTP_METHOD_POST)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result, _ := resp.Result(); string(result) != "file content" {
+			t.Fatalf("expected uploaded file content, got %q", result)
+		}
+	}
+}
+
+func TestCurlRedaction(t *testing.T) {
+	req := NewHttpClient().NewRequest("https://api.example.com").SetQueryParam("api_key", "k1").
+		SetQueryParam("page", "2").SetAuth(BasicAuth("user", "p1"))
+
+	// 未指定时不执行认证
+	command, err := req.Curl(HTTP_METHOD_GET)
+	if err != nil || strings.Contains(command, "Authorization") {
+		t.Fatalf("unexpected command %s %v", command, err)
+	}
+
+	// 认证头和查询参数中的密钥被脱敏
+	command, err = req.Curl(HTTP_METHOD_GET, WithCurlAuth(), WithCurlRedaction(), WithCurlRedactQueryParams("api_key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(command, "k1") || strings.Contains(command, "dXNlcjpwMQ") {
+		t.Fatalf("secret leaked in %s", command)
+	}
+	if !strings.Contains(command, "'Authorization: "+LOG_REDACTED+"'") || !strings.Contains(command, "page=2") {
+		t.Fatalf("unexpected command %s", command)
+	}
+	if strings.HasPrefix(command, "curl -X") {
+		t.Fatalf("GET without body should omit -X: %s", command)
+	}
+}
+
+func TestParseCurl(t *testing.T) {
+	server := newEchoServer()
+	defer server.Close()
+	dir := t.TempDir()
+	file := filepath.Join(dir, "avatar.png")
+	if err := os.WriteFile(file, []byte("png"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		command string
+		method  HttpMethod
+		expect  map[string]interface{}
+	}{
+		// 组合的无参数选项被忽略，-d默认使用POST
+		{`curl -sSL "` + server.URL + `/a" -d 'x=1' --data-urlencode "y=a b"`, HTTP_METHOD_POST,
+			map[string]interface{}{"body": "x=1&y=a+b", "type": CONTENT_TYPE_FORM}},
+		// -G把数据放到查询参数
+		{"curl -G " + server.URL + " -d x=1 \\\n  -d x=2", HTTP_METHOD_GET,
+			map[string]interface{}{"query": map[string]interface{}{"x": []interface{}{"1", "2"}}, "body": ""}},
+		// -u转为Basic认证，-XPATCH紧贴参数
+		{"curl -XPATCH -u user:pass --json '{\"a\":1}' --url " + server.URL, HTTP_METHOD_PATCH,
+			map[string]interface{}{"auth": "Basic dXNlcjpwYXNz", "body": `{"a":1}`, "type": CONTENT_TYPE_JSON}},
+		// ANSI-C引号
+		{"curl " + server.URL + " -H $'X-Trace: a\\x27b' -I", HTTP_METHOD_HEAD, nil},
+	}
+	for _, c := range cases {
+		req, method, err := ParseCurl(c.command)
+		if err != nil {
+			t.Fatalf("%s: %v", c.command, err)
+		}
+		if method != c.method {
+			t.Fatalf("%s: expected %s, got %s", c.command, c.method, method)
+		}
+		resp, err := req.Do(method)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var echo map[string]interface{}
+		result, _ := resp.Result()
+		json.Unmarshal(result, &echo)
+		for key, value := range c.expect {
+			if !reflect.DeepEqual(echo[key], value) {
+				t.Fatalf("%s: expected %s=%v, got %v", c.command, key, value, echo[key])
+			}
+		}
+	}
+
+	// 上传文件
+	req, method, err := ParseCurl("curl -F name=bob -F 'avatar=@" + file + ";type=image/png' " + server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := req.Do(method)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, _ := resp.Result()
+	if method != HTTP_METHOD_POST || !strings.Contains(string(result), `filename=\"avatar.png\"`) || !strings.Contains(string(result), "bob") {
+		t.Fatalf("unexpected multipart request %s", result)
+	}
+
+	for _, command := range []string{"curl -s", "curl --proxy http://p " + server.URL, "curl 'unterminated", "curl -H " + server.URL} {
+		if _, _, err := ParseCurl(command); err == nil {
+			t.Fatalf("expected error for %q", command)
+		}
+	}
+}
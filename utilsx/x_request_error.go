@@ -0,0 +1,117 @@
+package utilsx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// HTTP_ERROR_MAX_BODY_SIZE is the number of response body bytes kept in an HttpError.
+const HTTP_ERROR_MAX_BODY_SIZE int = 4096
+
+// HttpError is returned by Do and SuccessResult when the response status code
+// is 400 or above. Use errors.As to inspect it.
+type HttpError struct {
+	StatusCode int         // response status code
+	Status     string      // response status text, e.g. "404 Not Found"
+	Method     string      // request method
+	URL        string      // request url
+	Header     http.Header // response headers
+	Body       []byte      // response body truncated to HTTP_ERROR_MAX_BODY_SIZE bytes
+	Attempts   int         // attempts made before giving up
+}
+
+// newHttpError builds an HttpError from the current response of the request.
+//
+// It returns nil when the response status code is below 400.
+func (r *ApiRequest) newHttpError() *HttpError {
+	if r.apiResponseStatusCode < http.StatusBadRequest {
+		return nil
+	}
+	body := r.apiResponseData
+	if len(body) > HTTP_ERROR_MAX_BODY_SIZE {
+		body = body[:HTTP_ERROR_MAX_BODY_SIZE]
+	}
+	httpErr := &HttpError{
+		StatusCode: r.apiResponseStatusCode,
+		Status:     r.apiResponseStatus,
+		Method:     r.method,
+		URL:        r.GetUrl(),
+		Body:       append([]byte(nil), body...),
+		Attempts:   r.attempts,
+	}
+	if r.apiResponse != nil {
+		httpErr.Header = r.apiResponse.Header.Clone()
+	}
+	return httpErr
+}
+
+// Error implements the error interface.
+func (e *HttpError) Error() string {
+	status := e.Status
+	if status == "" {
+		status = fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("utilsx: %s %s returned %s: %s", e.Method, e.URL, status, string(e.Body))
+}
+
+// IsStatus reports whether err is an HttpError with one of the given status codes.
+func IsStatus(err error, statusCodes ...int) bool {
+	var httpErr *HttpError
+	if !errors.As(err, &httpErr) {
+		return false
+	}
+	for _, statusCode := range statusCodes {
+		if httpErr.StatusCode == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// IsNotFound reports whether err is an HttpError with status 404.
+func IsNotFound(err error) bool {
+	return IsStatus(err, http.StatusNotFound)
+}
+
+// IsUnauthorized reports whether err is an HttpError with status 401 or 403.
+func IsUnauthorized(err error) bool {
+	return IsStatus(err, http.StatusUnauthorized, http.StatusForbidden)
+}
+
+// IsTimeout reports whether err was caused by a timeout, either locally
+// (deadline or network timeout) or reported by the server with 408 or 504.
+func IsTimeout(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrRequestTimeout) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return IsStatus(err, http.StatusRequestTimeout, http.StatusGatewayTimeout)
+}
+
+// IsRetryable reports whether the failed request is worth retrying later:
+// transient network errors and 408, 429, 500, 502, 503 and 504 responses.
+// Cancellation by the caller is never retryable.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, ErrRequestCanceled) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var httpErr *HttpError
+	if errors.As(err, &httpErr) {
+		switch httpErr.StatusCode {
+		case http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusInternalServerError,
+			http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	return IsTimeout(err) || isRetryableError(err)
+}
@@ -0,0 +1,56 @@
+package utilsx
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHttpError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "test")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(strings.Repeat("x", HTTP_ERROR_MAX_BODY_SIZE+1)))
+	}))
+	defer server.Close()
+
+	resp, err := NewHttpRequest(server.URL).SetUri("users/1").Do(HTTP_METHOD_GET)
+	var httpErr *HttpError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("expected HttpError, got %v", err)
+	}
+	if httpErr.StatusCode != http.StatusNotFound || httpErr.Method != "GET" ||
+		httpErr.URL != server.URL+"/users/1" || httpErr.Header.Get("X-Request-Id") != "test" ||
+		len(httpErr.Body) != HTTP_ERROR_MAX_BODY_SIZE || httpErr.Attempts != 1 {
+		t.Fatalf("unexpected HttpError: %+v", httpErr)
+	}
+	if !IsNotFound(err) || IsRetryable(err) || IsTimeout(err) {
+		t.Fatal("unexpected error classification")
+	}
+
+	// 失败的响应依然可以读取
+	if resp == nil || resp.Success() {
+		t.Fatal("expected failed response")
+	}
+	if _, err := resp.SuccessResult(); !IsNotFound(err) {
+		t.Fatalf("expected not found from SuccessResult, got %v", err)
+	}
+}
+
+func TestHttpErrorTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	_, err := NewHttpRequest(server.URL).SetTimeout(10 * time.Millisecond).Do(HTTP_METHOD_GET)
+	if !IsTimeout(err) || !IsRetryable(err) {
+		t.Fatalf("expected retryable timeout, got %v", err)
+	}
+}
@@ -0,0 +1,379 @@
+package utilsx
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	IDEMPOTENCY_DEFAULT_HEADER   string        = "Idempotency-Key"
+	IDEMPOTENCY_REPLAYED_HEADER  string        = "Idempotent-Replayed"
+	IDEMPOTENCY_DEFAULT_TTL      time.Duration = 24 * time.Hour
+	IDEMPOTENCY_DEFAULT_LOCK_TTL time.Duration = time.Minute
+	IDEMPOTENCY_MAX_KEY_SIZE     int           = 255
+	IDEMPOTENCY_MAX_BODY_SIZE    int64         = 1024 * 1024
+)
+
+// IdempotencyStore keeps the responses of the requests seen by an IdempotencyGuard.
+// Its keys are the idempotency keys prefixed with the scope of their caller.
+//
+// redisx.IdempotencyStore implements it for guards running on several replicas.
+type IdempotencyStore interface {
+	// Reserve claims key for ttl while its first request is handled. It
+	// returns true when the key was free, otherwise the stored response, nil
+	// while the first request is still in progress.
+	Reserve(ctx context.Context, key string, ttl time.Duration) (bool, []byte, error)
+	// Complete stores the response of key for ttl.
+	Complete(ctx context.Context, key string, response []byte, ttl time.Duration) error
+	// Release frees key, so that the request can be sent again.
+	Release(ctx context.Context, key string) error
+}
+
+// IdempotencySetting configures an IdempotencyGuard.
+type IdempotencySetting struct {
+	Header   string           // header of the idempotency key, IDEMPOTENCY_DEFAULT_HEADER when empty
+	TTL      time.Duration    // how long responses are replayed, IDEMPOTENCY_DEFAULT_TTL when 0
+	LockTTL  time.Duration    // how long a request in progress holds its key, IDEMPOTENCY_DEFAULT_LOCK_TTL when 0
+	Required bool             // reject POST and PATCH requests without key with 400 Bad Request
+	Store    IdempotencyStore // in memory when nil
+	// Scope returns the identity of the caller of a request, so that callers
+	// cannot replay the responses of each other, IdempotencyScopeByAuthorization when nil.
+	Scope func(req *http.Request) string
+}
+
+// IdempotencyGuard makes the POST and PATCH handlers of a server idempotent.
+//
+// The first response to a key is stored and replayed, with the
+// IDEMPOTENCY_REPLAYED_HEADER header, to the requests repeating the key
+// within the TTL. Keys are scoped to the caller returned by the Scope setting.
+// A duplicate arriving while the first request is handled gets 409 Conflict,
+// and one with a different method, path, query or body gets 422
+// Unprocessable Entity. 5xx responses are not stored, so that they can be retried.
+type IdempotencyGuard struct {
+	setting IdempotencySetting
+}
+
+// idempotentResponse is a stored response.
+type idempotentResponse struct {
+	Fingerprint string      `json:"fingerprint"`
+	StatusCode  int         `json:"status_code"`
+	Header      http.Header `json:"header"`
+	Body        []byte      `json:"body"`
+}
+
+// idempotencyRecorder captures the response written by the handler.
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+	overflow   bool
+}
+
+type memoryIdempotencyStore struct {
+	mu        sync.Mutex
+	entries   map[string]memoryIdempotencyEntry
+	lastSweep time.Time
+}
+
+type memoryIdempotencyEntry struct {
+	response []byte
+	expiry   time.Time
+}
+
+type idempotencyKeyContextKey struct{}
+
+// WithIdempotencyKey sends an idempotency key with the POST and PATCH requests of the client.
+//
+// A new key is generated by each Do call and reused by all its attempts, so
+// BackoffRetryPolicy also retries these requests, while sending the apiRequest
+// again is a new operation. A key set by SetIdempotencyKey is used instead.
+//
+// Parameters:
+//   - header: the header of the key, IDEMPOTENCY_DEFAULT_HEADER when empty.
+func WithIdempotencyKey(header string) HttpClientOption {
+	return func(c *HttpClient) {
+		if header == "" {
+			header = IDEMPOTENCY_DEFAULT_HEADER
+		}
+		c.idempotencyHeader = header
+	}
+}
+
+// SetIdempotencyKey sets the idempotency key of the apiRequest, sent with every
+// attempt of every Do call whatever the method, in the header set by
+// WithIdempotencyKey or IDEMPOTENCY_DEFAULT_HEADER.
+//
+// Parameters:
+//   - key: the idempotency key, such as one made by NewIdempotencyKey.
+//
+// Returns:
+//   - executableApiRequest: The modified apiRequest struct.
+func (r *ApiRequest) SetIdempotencyKey(key string) ExecutableApiRequest {
+	r.idempotencyKey = key
+	return r
+}
+
+// NewIdempotencyKey returns a random UUID (version 4) usable as an idempotency key.
+func NewIdempotencyKey() string {
+	var id [16]byte
+	rand.Read(id[:])
+	id[6] = id[6]&0x0f | 0x40
+	id[8] = id[8]&0x3f | 0x80
+	encoded := hex.EncodeToString(id[:])
+	return encoded[:8] + "-" + encoded[8:12] + "-" + encoded[12:16] + "-" + encoded[16:20] + "-" + encoded[20:]
+}
+
+// IdempotencyKeyFromContext returns the idempotency key of the request sent
+// with ctx, empty when it has none. Middlewares and retry policies get it
+// from the request context.
+func IdempotencyKeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyKeyContextKey{}).(string)
+	return key
+}
+
+// withIdempotencyKey adds the idempotency key of the call to ctx and header.
+//
+// It returns the context and the headers of the call.
+func (r *ApiRequest) withIdempotencyKey(ctx context.Context, method HttpMethod, header http.Header) (context.Context, http.Header) {
+	// a generated key only lives for this call, the apiRequest keeps the key set by SetIdempotencyKey
+	key := r.idempotencyKey
+	if key == "" && r.client.idempotencyHeader != "" && !isIdempotentMethod(string(method)) {
+		key = NewIdempotencyKey()
+	}
+	if key == "" {
+		return ctx, header
+	}
+	name := r.client.idempotencyHeader
+	if name == "" {
+		name = IDEMPOTENCY_DEFAULT_HEADER
+	}
+	header = header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	header.Set(name, key)
+	return context.WithValue(ctx, idempotencyKeyContextKey{}, key), header
+}
+
+// NewIdempotencyGuard creates a new IdempotencyGuard.
+//
+// It takes the idempotency setting.
+// It returns a pointer to the created IdempotencyGuard.
+func NewIdempotencyGuard(setting IdempotencySetting) *IdempotencyGuard {
+	if setting.Header == "" {
+		setting.Header = IDEMPOTENCY_DEFAULT_HEADER
+	}
+	if setting.TTL <= 0 {
+		setting.TTL = IDEMPOTENCY_DEFAULT_TTL
+	}
+	if setting.LockTTL <= 0 {
+		setting.LockTTL = IDEMPOTENCY_DEFAULT_LOCK_TTL
+	}
+	if setting.Store == nil {
+		setting.Store = NewMemoryIdempotencyStore()
+	}
+	if setting.Scope == nil {
+		setting.Scope = IdempotencyScopeByAuthorization
+	}
+	return &IdempotencyGuard{setting: setting}
+}
+
+// IdempotencyScopeByAuthorization scopes idempotency keys by the Authorization
+// header of the request, hashed so that credentials are not stored.
+func IdempotencyScopeByAuthorization(req *http.Request) string {
+	authorization := req.Header.Get("Authorization")
+	if authorization == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(authorization))
+	return hex.EncodeToString(sum[:16])
+}
+
+// NewMemoryIdempotencyStore creates an in-process IdempotencyStore.
+func NewMemoryIdempotencyStore() IdempotencyStore {
+	return &memoryIdempotencyStore{entries: make(map[string]memoryIdempotencyEntry)}
+}
+
+// Middleware applies the guard to the POST and PATCH requests carrying a key.
+func (g *IdempotencyGuard) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		key := req.Header.Get(g.setting.Header)
+		if isIdempotentMethod(req.Method) || (key == "" && !g.setting.Required) {
+			next.ServeHTTP(w, req)
+			return
+		}
+		if key == "" || len(key) > IDEMPOTENCY_MAX_KEY_SIZE {
+			http.Error(w, fmt.Sprintf("missing or invalid %s header", g.setting.Header), http.StatusBadRequest)
+			return
+		}
+		fingerprint, err := idempotencyFingerprint(req)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+
+		ctx := req.Context()
+		key = g.setting.Scope(req) + ":" + key
+		reserved, stored, err := g.setting.Store.Reserve(ctx, key, g.setting.LockTTL)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+		if !reserved {
+			g.replay(w, stored, fingerprint)
+			return
+		}
+
+		recorder := &idempotencyRecorder{ResponseWriter: w}
+		completed := false
+		defer func() {
+			// the key is freed when the handler panics or fails, so the client can retry
+			if !completed {
+				g.setting.Store.Release(context.WithoutCancel(ctx), key)
+			}
+		}()
+		next.ServeHTTP(recorder, req)
+
+		statusCode := recorder.status()
+		if statusCode >= http.StatusInternalServerError || recorder.overflow {
+			return
+		}
+		response, err := json.Marshal(idempotentResponse{
+			Fingerprint: fingerprint,
+			StatusCode:  statusCode,
+			Header:      recorder.Header().Clone(),
+			Body:        recorder.body.Bytes(),
+		})
+		if err != nil {
+			return
+		}
+		completed = g.setting.Store.Complete(context.WithoutCancel(ctx), key, response, g.setting.TTL) == nil
+	})
+}
+
+// replay writes a stored response, or the error of a conflicting duplicate.
+func (g *IdempotencyGuard) replay(w http.ResponseWriter, stored []byte, fingerprint string) {
+	if stored == nil {
+		http.Error(w, "a request with the same idempotency key is in progress", http.StatusConflict)
+		return
+	}
+	var response idempotentResponse
+	if err := json.Unmarshal(stored, &response); err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	if response.Fingerprint != fingerprint {
+		http.Error(w, "the idempotency key was used by a different request", http.StatusUnprocessableEntity)
+		return
+	}
+	for name, values := range response.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set(IDEMPOTENCY_REPLAYED_HEADER, "true")
+	w.WriteHeader(response.StatusCode)
+	w.Write(response.Body)
+}
+
+// idempotencyFingerprint hashes the method, path, query and body of a request, and
+// restores the body, at most IDEMPOTENCY_MAX_BODY_SIZE bytes, for the handler.
+func idempotencyFingerprint(req *http.Request) (string, error) {
+	hash := sha256.New()
+	io.WriteString(hash, req.Method+" "+req.URL.EscapedPath()+"?"+req.URL.RawQuery+"\n")
+	if req.Body != nil {
+		body, err := io.ReadAll(io.LimitReader(req.Body, IDEMPOTENCY_MAX_BODY_SIZE+1))
+		req.Body.Close()
+		if err != nil {
+			return "", err
+		}
+		if int64(len(body)) > IDEMPOTENCY_MAX_BODY_SIZE {
+			return "", errors.New("utilsx: idempotent request body too large")
+		}
+		hash.Write(body)
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (r *idempotencyRecorder) WriteHeader(statusCode int) {
+	if r.statusCode == 0 {
+		r.statusCode = statusCode
+	}
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write implements http.ResponseWriter.
+func (r *idempotencyRecorder) Write(data []byte) (int, error) {
+	if r.statusCode == 0 {
+		r.statusCode = http.StatusOK
+	}
+	if !r.overflow {
+		if int64(r.body.Len()+len(data)) > IDEMPOTENCY_MAX_BODY_SIZE {
+			// too large to be stored, the response is not replayed
+			r.overflow = true
+			r.body.Reset()
+		} else {
+			r.body.Write(data)
+		}
+	}
+	return r.ResponseWriter.Write(data)
+}
+
+// Unwrap returns the wrapped writer, for http.ResponseController.
+func (r *idempotencyRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// status returns the written status code, 200 when the handler wrote nothing.
+func (r *idempotencyRecorder) status() int {
+	if r.statusCode == 0 {
+		return http.StatusOK
+	}
+	return r.statusCode
+}
+
+// Reserve implements IdempotencyStore.
+func (s *memoryIdempotencyStore) Reserve(_ context.Context, key string, ttl time.Duration) (bool, []byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if now.Sub(s.lastSweep) > IDEMPOTENCY_DEFAULT_LOCK_TTL {
+		for stored, entry := range s.entries {
+			if now.After(entry.expiry) {
+				delete(s.entries, stored)
+			}
+		}
+		s.lastSweep = now
+	}
+	if entry, ok := s.entries[key]; ok && now.Before(entry.expiry) {
+		return false, entry.response, nil
+	}
+	s.entries[key] = memoryIdempotencyEntry{expiry: now.Add(ttl)}
+	return true, nil, nil
+}
+
+// Complete implements IdempotencyStore.
+func (s *memoryIdempotencyStore) Complete(_ context.Context, key string, response []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = memoryIdempotencyEntry{response: response, expiry: time.Now().Add(ttl)}
+	return nil
+}
+
+// Release implements IdempotencyStore.
+func (s *memoryIdempotencyStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
@@ -0,0 +1,175 @@
+package utilsx
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyKeyRetries(t *testing.T) {
+	var attempts int32
+	keys := make(chan string, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys <- r.Header.Get("X-Request-Key")
+		if atomic.AddInt32(&attempts, 1)%3 != 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	// 同一个请求的所有重试使用相同的key，POST带key时允许重试
+	client := NewHttpClient(WithIdempotencyKey("X-Request-Key"))
+	policy := &BackoffRetryPolicy{MaxAttempts: 3, RetryStatusCodes: []int{http.StatusServiceUnavailable}}
+	if _, err := client.NewRequest(server.URL).SetRetryPolicy(policy).Do(HTTP_METHOD_POST); err != nil {
+		t.Fatal(err)
+	}
+	first := <-keys
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(first) {
+		t.Fatalf("unexpected key %q", first)
+	}
+	for i := 0; i < 2; i++ {
+		if key := <-keys; key != first {
+			t.Fatalf("expected stable key %s, got %s", first, key)
+		}
+	}
+
+	// 同一个请求再次发送时是新的操作，生成新的key
+	req := client.NewRequest(server.URL)
+	req.Do(HTTP_METHOD_POST)
+	req.Do(HTTP_METHOD_POST)
+	if firstSend, secondSend := <-keys, <-keys; firstSend == "" || firstSend == secondSend {
+		t.Fatalf("expected a new key per send, got %q and %q", firstSend, secondSend)
+	}
+
+	// 新的请求生成新的key，幂等方法不生成key
+	client.NewRequest(server.URL).Do(HTTP_METHOD_PATCH)
+	if key := <-keys; key == "" || key == first {
+		t.Fatalf("expected a new key, got %q", key)
+	}
+	client.NewRequest(server.URL).Do(HTTP_METHOD_PUT)
+	if key := <-keys; key != "" {
+		t.Fatalf("expected no key for PUT, got %q", key)
+	}
+
+	// 未开启自动生成时可手动设置，且POST不带key时不重试
+	atomic.StoreInt32(&attempts, 0)
+	NewHttpClient().NewRequest(server.URL).SetRetryPolicy(policy).Do(HTTP_METHOD_POST)
+	if atomic.LoadInt32(&attempts) != 1 {
+		t.Fatalf("POST without key should not be retried, got %d attempts", attempts)
+	}
+	<-keys
+	NewHttpClient().NewRequest(server.URL).SetIdempotencyKey("order-1").Use(func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get(IDEMPOTENCY_DEFAULT_HEADER) != "order-1" || IdempotencyKeyFromContext(req.Context()) != "order-1" {
+				t.Errorf("unexpected key %q", req.Header.Get(IDEMPOTENCY_DEFAULT_HEADER))
+			}
+			return next(req)
+		}
+	}).Do(HTTP_METHOD_POST)
+}
+
+func TestIdempotencyGuard(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	guard := NewIdempotencyGuard(IdempotencySetting{})
+	server := httptest.NewServer(guard.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		count := atomic.AddInt32(&calls, 1)
+		switch string(body) {
+		case "slow":
+			<-release
+		case "fail":
+			if count == 1 {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		}
+		w.Header().Set("X-Order", "o-1")
+		w.WriteHeader(http.StatusCreated)
+		w.Write(append([]byte("created "), body...))
+	})))
+	defer server.Close()
+
+	sendAs := func(key, target, authorization, body string) (int, string, http.Header) {
+		req, _ := http.NewRequest(http.MethodPost, server.URL+target, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(IDEMPOTENCY_DEFAULT_HEADER, key)
+		}
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(data), resp.Header
+	}
+	send := func(key, body string) (int, string, http.Header) {
+		return sendAs(key, "/orders", "", body)
+	}
+
+	// 重复的key回放第一次的响应，不再调用处理函数
+	status, body, _ := send("k1", "a")
+	replayStatus, replayBody, header := send("k1", "a")
+	if status != http.StatusCreated || replayStatus != status || replayBody != body || header.Get("X-Order") != "o-1" ||
+		header.Get(IDEMPOTENCY_REPLAYED_HEADER) != "true" || atomic.LoadInt32(&calls) != 1 {
+		t.Fatalf("expected replayed response, got %d %s %v", replayStatus, replayBody, header)
+	}
+
+	// 相同key不同请求体返回422
+	if status, _, _ = send("k1", "b"); status != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d", status)
+	}
+
+	// 相同key不同查询参数返回422
+	if status, _, _ = sendAs("k1", "/orders?dry_run=1", "", "a"); status != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422 for a different query, got %d", status)
+	}
+
+	// key按调用方隔离，其他调用方不能回放别人的响应
+	if status, _, header = sendAs("k1", "/orders", "Bearer other", "a"); status != http.StatusCreated ||
+		header.Get(IDEMPOTENCY_REPLAYED_HEADER) != "" || atomic.LoadInt32(&calls) != 2 {
+		t.Fatalf("expected a new response for another caller, got %d %v", status, header)
+	}
+
+	// 没有key的请求直接处理
+	send("", "a")
+	send("", "a")
+	if atomic.LoadInt32(&calls) != 4 {
+		t.Fatalf("expected requests without key to be handled, got %d calls", calls)
+	}
+
+	// 5xx响应不保存，可以重试
+	atomic.StoreInt32(&calls, 0)
+	if status, _, _ = send("k2", "fail"); status != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", status)
+	}
+	if status, _, _ = send("k2", "fail"); status != http.StatusCreated {
+		t.Fatalf("expected retried request to succeed, got %d", status)
+	}
+
+	// 第一次请求处理中时，重复请求返回409
+	atomic.StoreInt32(&calls, 0)
+	done := make(chan int)
+	go func() {
+		status, _, _ := send("k3", "slow")
+		done <- status
+	}()
+	for atomic.LoadInt32(&calls) == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if status, _, _ = send("k3", "slow"); status != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", status)
+	}
+	close(release)
+	if status = <-done; status != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", status)
+	}
+}
@@ -0,0 +1,173 @@
+package utilsx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+type jsonDecodeOptions struct {
+	disallowUnknownFields bool
+	useNumber             bool
+}
+
+type DecodeOption func(*jsonDecodeOptions)
+
+// ApiError is returned by DoJSONWithError and DoJSONWithErrorContext when a
+// non-2xx response body was decoded into the error body type E.
+type ApiError[E any] struct {
+	StatusCode int        // response status code
+	Body       E          // decoded error body
+	RawBody    []byte     // undecoded response body
+	Err        *HttpError // underlying failed response, nil for non-2xx statuses below 400
+}
+
+// WithDisallowUnknownFields makes decoding fail when the JSON contains a field
+// that does not exist in the destination type.
+func WithDisallowUnknownFields() DecodeOption {
+	return func(o *jsonDecodeOptions) {
+		o.disallowUnknownFields = true
+	}
+}
+
+// WithUseNumber decodes JSON numbers stored in interface{} values as json.Number
+// instead of float64.
+func WithUseNumber() DecodeOption {
+	return func(o *jsonDecodeOptions) {
+		o.useNumber = true
+	}
+}
+
+// DoJSON sends the request and decodes a 2xx response body into T.
+//
+// It is a shorthand for DoJSONContext with context.Background().
+func DoJSON[T any](req ExecutableApiRequest, method HttpMethod, opts ...DecodeOption) (T, error) {
+	return DoJSONContext[T](context.Background(), req, method, opts...)
+}
+
+// DoJSONContext sends the request bound to ctx and decodes a 2xx response body into T.
+//
+// Parameters:
+//   - ctx: the context of the request.
+//   - req: the request to send.
+//   - method: the HTTP method of the request.
+//   - opts: the decode options.
+//
+// Returns:
+//   - T: the decoded body, the zero value when the body is empty.
+//   - error: the request error, an *HttpError for failed responses, an error
+//     for other non-2xx responses or the decode error.
+func DoJSONContext[T any](ctx context.Context, req ExecutableApiRequest, method HttpMethod, opts ...DecodeOption) (T, error) {
+	var result T
+	resp, err := req.DoContext(ctx, method)
+	if err != nil {
+		return result, err
+	}
+	data, _ := resp.Result()
+	if !isSuccessStatus(resp.StatusCode()) {
+		return result, fmt.Errorf("utilsx: unexpected status %d: %s", resp.StatusCode(), string(data))
+	}
+	return DecodeJSON[T](data, opts...)
+}
+
+// DoJSONWithError sends the request and decodes a 2xx response body into T
+// and any other response body into the error body type E.
+//
+// It is a shorthand for DoJSONWithErrorContext with context.Background().
+func DoJSONWithError[T, E any](req ExecutableApiRequest, method HttpMethod, opts ...DecodeOption) (T, error) {
+	return DoJSONWithErrorContext[T, E](context.Background(), req, method, opts...)
+}
+
+// DoJSONWithErrorContext sends the request bound to ctx and decodes a 2xx
+// response body into T and any other response body into the error body type E.
+//
+// Parameters:
+//   - ctx: the context of the request.
+//   - req: the request to send.
+//   - method: the HTTP method of the request.
+//   - opts: the decode options, applied to both T and E.
+//
+// Returns:
+//   - T: the decoded body, the zero value when the body is empty.
+//   - error: *ApiError[E] for non-2xx responses whose body decodes into E,
+//     otherwise the request or decode error.
+func DoJSONWithErrorContext[T, E any](ctx context.Context, req ExecutableApiRequest, method HttpMethod, opts ...DecodeOption) (T, error) {
+	var result T
+	resp, err := req.DoContext(ctx, method)
+	var httpErr *HttpError
+	if err != nil && !errors.As(err, &httpErr) {
+		return result, err
+	}
+	data, _ := resp.Result()
+	if !isSuccessStatus(resp.StatusCode()) {
+		errBody, decodeErr := DecodeJSON[E](data, opts...)
+		if decodeErr != nil {
+			if httpErr != nil {
+				return result, httpErr
+			}
+			return result, fmt.Errorf("utilsx: unexpected status %d: %s", resp.StatusCode(), string(data))
+		}
+		return result, &ApiError[E]{StatusCode: resp.StatusCode(), Body: errBody, RawBody: data, Err: httpErr}
+	}
+	return DecodeJSON[T](data, opts...)
+}
+
+// DecodeJSON decodes data into a value of type T.
+//
+// Empty data decodes into the zero value of T. Trailing data after the first
+// JSON value is rejected.
+//
+// Parameters:
+//   - data: the JSON document.
+//   - opts: the decode options.
+//
+// Returns:
+//   - T: the decoded value.
+//   - error: the decode error, if any.
+func DecodeJSON[T any](data []byte, opts ...DecodeOption) (T, error) {
+	var result T
+	if len(bytes.TrimSpace(data)) == 0 {
+		return result, nil
+	}
+	var options jsonDecodeOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if options.disallowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+	if options.useNumber {
+		decoder.UseNumber()
+	}
+	if err := decoder.Decode(&result); err != nil {
+		return result, fmt.Errorf("utilsx: decode json response: %w", err)
+	}
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return result, errors.New("utilsx: decode json response: unexpected data after top-level value")
+	}
+	return result, nil
+}
+
+// Error implements the error interface.
+func (e *ApiError[E]) Error() string {
+	return fmt.Sprintf("utilsx: unexpected status %d: %s", e.StatusCode, string(e.RawBody))
+}
+
+// Unwrap returns the underlying *HttpError, if any.
+func (e *ApiError[E]) Unwrap() error {
+	if e.Err == nil {
+		return nil
+	}
+	return e.Err
+}
+
+// isSuccessStatus reports whether statusCode is in the 2xx range.
+func isSuccessStatus(statusCode int) bool {
+	return statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices
+}
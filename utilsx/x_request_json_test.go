@@ -0,0 +1,56 @@
+package utilsx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type testJsonUser struct {
+	Id   uint64 `json:"id"`
+	Name string `json:"name"`
+}
+
+type testJsonError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func TestDoJSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"code":"not_found","message":"user not found"}`))
+			return
+		}
+		w.Write([]byte(`{"id":1,"name":"golix","extra":true}`))
+	}))
+	defer server.Close()
+
+	user, err := DoJSON[testJsonUser](NewHttpRequest(server.URL), HTTP_METHOD_GET)
+	if err != nil || user.Id != 1 || user.Name != "golix" {
+		t.Fatalf("unexpected result: %+v %v", user, err)
+	}
+
+	// 严格模式下未知字段会导致解析失败
+	_, err = DoJSON[testJsonUser](NewHttpRequest(server.URL), HTTP_METHOD_GET, WithDisallowUnknownFields())
+	if err == nil {
+		t.Fatal("expected unknown field error")
+	}
+
+	_, err = DoJSONWithError[testJsonUser, testJsonError](NewHttpRequest(server.URL).SetUri("missing"), HTTP_METHOD_GET)
+	var apiErr *ApiError[testJsonError]
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.Body.Code != "not_found" {
+		t.Fatalf("expected decoded error body, got %v", err)
+	}
+
+	// 已取消的ctx直接返回请求错误
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = DoJSONWithErrorContext[testJsonUser, testJsonError](ctx, NewHttpRequest(server.URL), HTTP_METHOD_GET)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected canceled error, got %v", err)
+	}
+}
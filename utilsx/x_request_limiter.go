@@ -0,0 +1,134 @@
+package utilsx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrInvalidRateLimit is returned when a rate limiter is created with a rate
+// that is not positive or a burst below 1.
+var ErrInvalidRateLimit = errors.New("utilsx: rate limit needs a positive rate and a burst of at least 1")
+
+// RateLimiter blocks until a request identified by key may be sent.
+//
+// Wait must return an error once ctx is done. redisx.RateLimiter implements
+// it with a quota shared by every replica.
+type RateLimiter interface {
+	Wait(ctx context.Context, key string) error
+}
+
+// RateLimitKeyFunc returns the quota key of a request.
+type RateLimitKeyFunc func(req *http.Request) string
+
+// TokenBucketLimiter is an in-process RateLimiter keeping one token bucket per key.
+type TokenBucketLimiter struct {
+	rate    float64 // tokens added per second
+	burst   float64 // bucket capacity
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	now     func() time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucketLimiter creates a new TokenBucketLimiter.
+//
+// Parameters:
+//   - rate: the number of requests allowed per second, must be positive.
+//   - burst: the number of requests allowed at once, at least 1.
+//
+// Returns:
+//   - *TokenBucketLimiter: the created limiter.
+//   - error: ErrInvalidRateLimit for an invalid rate or burst.
+func NewTokenBucketLimiter(rate float64, burst int) (*TokenBucketLimiter, error) {
+	if !(rate > 0) || burst < 1 {
+		return nil, fmt.Errorf("%w: rate %v, burst %d", ErrInvalidRateLimit, rate, burst)
+	}
+	return &TokenBucketLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}, nil
+}
+
+// Wait implements RateLimiter.
+func (l *TokenBucketLimiter) Wait(ctx context.Context, key string) error {
+	for {
+		delay := l.reserve(key)
+		if delay == 0 {
+			return nil
+		}
+		if err := sleepContext(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// reserve takes a token from the bucket of key.
+//
+// It returns 0 when a token was taken, otherwise how long to wait for the next one.
+func (l *TokenBucketLimiter) reserve(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = bucket
+	}
+	bucket.tokens = math.Min(l.burst, bucket.tokens+now.Sub(bucket.last).Seconds()*l.rate)
+	bucket.last = now
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return 0
+	}
+	return time.Duration(math.Ceil((1 - bucket.tokens) / l.rate * float64(time.Second)))
+}
+
+// RateLimitByHost uses the request host as quota key, giving every host its own quota.
+func RateLimitByHost(req *http.Request) string {
+	return req.URL.Host
+}
+
+// RateLimitByKey uses a fixed quota key, sharing one quota between all requests.
+func RateLimitByKey(key string) RateLimitKeyFunc {
+	return func(*http.Request) string {
+		return key
+	}
+}
+
+// RateLimitMiddleware waits for the limiter before every attempt of a request.
+//
+// Parameters:
+//   - limiter: the rate limiter.
+//   - keyFunc: the quota key of a request, RateLimitByHost when nil.
+//
+// Returns:
+//   - Middleware: the rate limiting middleware.
+func RateLimitMiddleware(limiter RateLimiter, keyFunc RateLimitKeyFunc) Middleware {
+	if keyFunc == nil {
+		keyFunc = RateLimitByHost
+	}
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			if err := limiter.Wait(req.Context(), keyFunc(req)); err != nil {
+				return nil, err
+			}
+			return next(req)
+		}
+	}
+}
+
+// WithRateLimiter limits the requests of the client with a quota per host.
+func WithRateLimiter(limiter RateLimiter) HttpClientOption {
+	return WithMiddleware(RateLimitMiddleware(limiter, RateLimitByHost))
+}
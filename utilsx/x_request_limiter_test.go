@@ -0,0 +1,57 @@
+package utilsx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketLimiter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	limiter, err := NewTokenBucketLimiter(20, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewHttpClient(WithRateLimiter(limiter))
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if _, err := client.NewRequest(server.URL).Do(HTTP_METHOD_GET); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 突发2个请求后，每秒20个请求，剩余2个请求至少等待约100ms
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Fatalf("requests were not limited, took %s", elapsed)
+	}
+}
+
+func TestTokenBucketLimiterContext(t *testing.T) {
+	// 速率必须为正数，突发数至少为1
+	for _, invalid := range []struct {
+		rate  float64
+		burst int
+	}{{0, 1}, {-1, 1}, {1, 0}} {
+		if _, err := NewTokenBucketLimiter(invalid.rate, invalid.burst); !errors.Is(err, ErrInvalidRateLimit) {
+			t.Fatalf("expected ErrInvalidRateLimit for %v, got %v", invalid, err)
+		}
+	}
+
+	limiter, _ := NewTokenBucketLimiter(0.1, 1)
+	if err := limiter.Wait(context.Background(), "partner"); err != nil {
+		t.Fatal(err)
+	}
+	// 其他key的配额互不影响
+	if err := limiter.Wait(context.Background(), "other"); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx, "partner"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline error, got %v", err)
+	}
+}
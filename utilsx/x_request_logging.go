@@ -0,0 +1,297 @@
+package utilsx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	LOG_MAX_BODY_SIZE    int    = 4096        // default number of body bytes logged
+	LOG_MAX_CAPTURE_SIZE int    = 1024 * 1024 // bodies larger than this are never parsed for redaction
+	LOG_REDACTED         string = "[REDACTED]"
+)
+
+// Logger is the logging backend of LoggingMiddleware. *slog.Logger implements it.
+type Logger interface {
+	Log(ctx context.Context, level slog.Level, msg string, args ...any)
+}
+
+type logOptions struct {
+	logger        Logger
+	successLevel  slog.Level
+	failureLevel  slog.Level
+	slowLevel     slog.Level
+	slowThreshold time.Duration
+	onlyFailures  bool
+	bodyLimit     int
+	redactHeaders map[string]struct{}
+	redactPaths   [][]string
+}
+
+type LogOption func(*logOptions)
+
+type loggedBody struct {
+	io.ReadCloser
+	limit    int
+	captured bytes.Buffer
+	once     sync.Once
+	onClose  func(data []byte)
+}
+
+// defaultRedactHeaders are the headers redacted by LoggingMiddleware unless WithLogRedactHeaders is used.
+var defaultRedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+// WithLogger sets the logger, slog.Default() when not set.
+func WithLogger(logger Logger) LogOption {
+	return func(o *logOptions) {
+		o.logger = logger
+	}
+}
+
+// WithLogLevels sets the level of successful, failed and slow calls.
+//
+// A call fails when the transport returns an error or the response status code is 400 or above.
+func WithLogLevels(success, failure, slow slog.Level) LogOption {
+	return func(o *logOptions) {
+		o.successLevel = success
+		o.failureLevel = failure
+		o.slowLevel = slow
+	}
+}
+
+// WithLogSlowThreshold marks successful calls taking longer than threshold as slow.
+func WithLogSlowThreshold(threshold time.Duration) LogOption {
+	return func(o *logOptions) {
+		o.slowThreshold = threshold
+	}
+}
+
+// WithLogOnlyFailures skips successful calls, except the slow ones.
+func WithLogOnlyFailures() LogOption {
+	return func(o *logOptions) {
+		o.onlyFailures = true
+	}
+}
+
+// WithLogBodyLimit sets the number of body bytes logged, 0 disables body logging.
+func WithLogBodyLimit(limit int) LogOption {
+	return func(o *logOptions) {
+		o.bodyLimit = limit
+	}
+}
+
+// WithLogRedactHeaders replaces the list of headers whose values are redacted.
+func WithLogRedactHeaders(names ...string) LogOption {
+	return func(o *logOptions) {
+		o.redactHeaders = make(map[string]struct{}, len(names))
+		for _, name := range names {
+			o.redactHeaders[http.CanonicalHeaderKey(name)] = struct{}{}
+		}
+	}
+}
+
+// WithLogRedactJSONPaths redacts values of JSON request and response bodies.
+//
+// A path is a dot separated list of object keys, such as "user.password".
+// Paths go through arrays, so "items.card" redacts the card of every item.
+func WithLogRedactJSONPaths(paths ...string) LogOption {
+	return func(o *logOptions) {
+		for _, path := range paths {
+			o.redactPaths = append(o.redactPaths, strings.Split(path, "."))
+		}
+	}
+}
+
+// LoggingMiddleware logs every attempt of a request with structured attributes.
+//
+// Sensitive headers are redacted, see defaultRedactHeaders, and bodies are
+// truncated to LOG_MAX_BODY_SIZE bytes unless configured otherwise. The
+// response is logged once its body is closed, so streamed responses are not
+// buffered.
+//
+// Parameters:
+//   - opts: the logging options.
+//
+// Returns:
+//   - Middleware: the logging middleware.
+func LoggingMiddleware(opts ...LogOption) Middleware {
+	options := &logOptions{
+		successLevel: slog.LevelInfo,
+		failureLevel: slog.LevelError,
+		slowLevel:    slog.LevelWarn,
+		bodyLimit:    LOG_MAX_BODY_SIZE,
+	}
+	WithLogRedactHeaders(defaultRedactHeaders...)(options)
+	for _, opt := range opts {
+		opt(options)
+	}
+	if options.logger == nil {
+		options.logger = slog.Default()
+	}
+
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			attrs := []any{
+				slog.String("method", req.Method),
+				slog.String("url", req.URL.String()),
+				slog.Any("request_headers", options.redactHeader(req.Header)),
+			}
+			if options.bodyLimit > 0 {
+				attrs = append(attrs, slog.String("request_body", options.formatBody(requestBodySnapshot(req, options.captureLimit()))))
+			}
+
+			resp, err := next(req)
+			if err != nil {
+				attrs = append(attrs, slog.Duration("duration", time.Since(start)), slog.String("error", err.Error()))
+				options.logger.Log(req.Context(), options.failureLevel, "http request failed", attrs...)
+				return resp, err
+			}
+			resp.Body = &loggedBody{
+				ReadCloser: resp.Body,
+				limit:      options.captureLimit(),
+				onClose: func(data []byte) {
+					options.logResponse(req.Context(), resp, time.Since(start), data, attrs)
+				},
+			}
+			return resp, nil
+		}
+	}
+}
+
+// logResponse logs a completed call according to its outcome.
+func (o *logOptions) logResponse(ctx context.Context, resp *http.Response, duration time.Duration, data []byte, attrs []any) {
+	level, msg := o.successLevel, "http request"
+	switch {
+	case resp.StatusCode >= http.StatusBadRequest:
+		level, msg = o.failureLevel, "http request failed"
+	case o.slowThreshold > 0 && duration > o.slowThreshold:
+		level, msg = o.slowLevel, "http request slow"
+	case o.onlyFailures:
+		return
+	}
+	attrs = append(attrs,
+		slog.Int("status", resp.StatusCode),
+		slog.Duration("duration", duration),
+		slog.Any("response_headers", o.redactHeader(resp.Header)),
+	)
+	if o.bodyLimit > 0 {
+		attrs = append(attrs, slog.String("response_body", o.formatBody(data)))
+	}
+	o.logger.Log(ctx, level, msg, attrs...)
+}
+
+// captureLimit returns how many body bytes must be captured to log them.
+//
+// One byte more than the body limit is captured to detect truncation, and
+// JSON redaction needs the whole body, so more is captured when paths are set.
+func (o *logOptions) captureLimit() int {
+	if o.bodyLimit <= 0 {
+		return 0
+	}
+	if len(o.redactPaths) > 0 {
+		return max(o.bodyLimit+1, LOG_MAX_CAPTURE_SIZE)
+	}
+	return o.bodyLimit + 1
+}
+
+// redactHeader returns a copy of header with the sensitive values redacted.
+func (o *logOptions) redactHeader(header http.Header) http.Header {
+	redacted := header.Clone()
+	for name := range redacted {
+		if _, ok := o.redactHeaders[name]; ok {
+			redacted[name] = []string{LOG_REDACTED}
+		}
+	}
+	return redacted
+}
+
+// formatBody redacts the JSON paths of data and truncates it to the body limit.
+//
+// A JSON body that cannot be parsed, for example because it was too large to
+// be captured whole, is omitted when redaction paths are set.
+func (o *logOptions) formatBody(data []byte) string {
+	if len(o.redactPaths) > 0 {
+		trimmed := bytes.TrimSpace(data)
+		if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+			var document interface{}
+			if err := json.Unmarshal(trimmed, &document); err != nil {
+				return "[unparseable json body omitted]"
+			}
+			for _, path := range o.redactPaths {
+				redactJSONPath(document, path)
+			}
+			data, _ = json.Marshal(document)
+		}
+	}
+	if len(data) > o.bodyLimit {
+		return string(data[:o.bodyLimit]) + "...(truncated)"
+	}
+	return string(data)
+}
+
+// redactJSONPath replaces the value at path in a decoded JSON document.
+func redactJSONPath(document interface{}, path []string) {
+	switch value := document.(type) {
+	case map[string]interface{}:
+		child, ok := value[path[0]]
+		if !ok {
+			return
+		}
+		if len(path) == 1 {
+			value[path[0]] = LOG_REDACTED
+			return
+		}
+		redactJSONPath(child, path[1:])
+	case []interface{}:
+		for _, item := range value {
+			redactJSONPath(item, path)
+		}
+	}
+}
+
+// requestBodySnapshot returns at most limit bytes of the request body without
+// consuming it, or a placeholder for streamed bodies.
+func requestBodySnapshot(req *http.Request, limit int) []byte {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+	if req.GetBody == nil {
+		return []byte("<stream>")
+	}
+	if encoding := req.Header.Get("Content-Encoding"); encoding != "" {
+		return []byte("<" + encoding + " body>")
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil
+	}
+	defer body.Close()
+	data, _ := io.ReadAll(io.LimitReader(body, int64(limit)))
+	return data
+}
+
+// Read implements io.Reader, capturing the first bytes read up to the limit.
+func (b *loggedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if remaining := b.limit - b.captured.Len(); remaining > 0 && n > 0 {
+		b.captured.Write(p[:min(n, remaining)])
+	}
+	return n, err
+}
+
+// Close closes the body and logs the captured content once.
+func (b *loggedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() {
+		b.onClose(b.captured.Bytes())
+	})
+	return err
+}
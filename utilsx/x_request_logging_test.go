@@ -0,0 +1,80 @@
+package utilsx
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestLogServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+		w.Write([]byte(`{"token":"secret-token","items":[{"card":"4111"}],"name":"golix"}`))
+	}))
+}
+
+func TestLoggingMiddleware(t *testing.T) {
+	server := newTestLogServer()
+	defer server.Close()
+
+	var output bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&output, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	client := NewHttpClient(WithMiddleware(LoggingMiddleware(
+		WithLogger(logger),
+		WithLogRedactJSONPaths("password", "token", "items.card"),
+	)))
+	client.NewRequest(server.URL).
+		SetHeader("Authorization", "Bearer secret-bearer").
+		SetBody("password", "secret-password").
+		Do(HTTP_METHOD_POST)
+
+	logged := output.String()
+	for _, secret := range []string{"secret-bearer", "secret-password", "secret-token", "4111"} {
+		if strings.Contains(logged, secret) {
+			t.Fatalf("secret %q leaked into log %q", secret, logged)
+		}
+	}
+	if !strings.Contains(logged, "level=INFO") || !strings.Contains(logged, "status=200") || !strings.Contains(logged, "golix") {
+		t.Fatalf("unexpected log output %q", logged)
+	}
+}
+
+func TestLoggingMiddlewareOnlyFailures(t *testing.T) {
+	server := newTestLogServer()
+	defer server.Close()
+
+	var output bytes.Buffer
+	client := NewHttpClient(WithMiddleware(LoggingMiddleware(
+		WithLogger(slog.New(slog.NewTextHandler(&output, nil))),
+		WithLogOnlyFailures(),
+		WithLogBodyLimit(8),
+	)))
+
+	// 只记录失败的请求
+	client.NewRequest(server.URL).Do(HTTP_METHOD_GET)
+	if output.Len() != 0 {
+		t.Fatalf("successful call must not be logged, got %q", output.String())
+	}
+	client.NewRequest(server.URL).SetUri("fail").Do(HTTP_METHOD_GET)
+	if !strings.Contains(output.String(), "level=ERROR") || !strings.Contains(output.String(), "(truncated)") {
+		t.Fatalf("unexpected failure log %q", output.String())
+	}
+
+	// 慢请求即使成功也会记录
+	output.Reset()
+	slowClient := NewHttpClient(WithMiddleware(LoggingMiddleware(
+		WithLogger(slog.New(slog.NewTextHandler(&output, nil))),
+		WithLogOnlyFailures(),
+		WithLogSlowThreshold(time.Nanosecond),
+	)))
+	slowClient.NewRequest(server.URL).Do(HTTP_METHOD_GET)
+	if !strings.Contains(output.String(), "level=WARN") {
+		t.Fatalf("slow call must be logged, got %q", output.String())
+	}
+}
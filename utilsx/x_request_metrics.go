@@ -0,0 +1,70 @@
+package utilsx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+const (
+	METRIC_ERROR_TIMEOUT   string = "timeout"      // the attempt timed out
+	METRIC_ERROR_CANCELED  string = "canceled"     // the caller canceled the request
+	METRIC_ERROR_TRANSPORT string = "transport"    // connection, TLS or protocol error
+	METRIC_ERROR_CLIENT    string = "client_error" // 4xx response
+	METRIC_ERROR_SERVER    string = "server_error" // 5xx response
+)
+
+// HttpMetrics receives the measures of MetricsMiddleware.
+//
+// The otelx and prometheusx packages provide implementations.
+type HttpMetrics interface {
+	// ObserveDuration records the duration of an attempt until the response
+	// headers, statusCode is 0 when the attempt failed.
+	ObserveDuration(host, method string, statusCode int, duration time.Duration)
+	// CountError counts a failed attempt, reason is one of the METRIC_ERROR constants.
+	CountError(host, method, reason string)
+}
+
+// MetricsMiddleware records the latency and the errors of every attempt of a request, per host.
+//
+// Parameters:
+//   - metrics: the metrics backend.
+//
+// Returns:
+//   - Middleware: the metrics middleware.
+func MetricsMiddleware(metrics HttpMetrics) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next(req)
+			duration := time.Since(start)
+
+			host, method := req.URL.Host, req.Method
+			if err != nil {
+				metrics.ObserveDuration(host, method, 0, duration)
+				metrics.CountError(host, method, metricErrorReason(req.Context(), err))
+				return resp, err
+			}
+			metrics.ObserveDuration(host, method, resp.StatusCode, duration)
+			switch {
+			case resp.StatusCode >= http.StatusInternalServerError:
+				metrics.CountError(host, method, METRIC_ERROR_SERVER)
+			case resp.StatusCode >= http.StatusBadRequest:
+				metrics.CountError(host, method, METRIC_ERROR_CLIENT)
+			}
+			return resp, nil
+		}
+	}
+}
+
+// metricErrorReason classifies a transport error.
+func metricErrorReason(ctx context.Context, err error) string {
+	switch {
+	case errors.Is(ctx.Err(), context.Canceled):
+		return METRIC_ERROR_CANCELED
+	case IsTimeout(err):
+		return METRIC_ERROR_TIMEOUT
+	}
+	return METRIC_ERROR_TRANSPORT
+}
@@ -0,0 +1,73 @@
+package utilsx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testMetrics struct {
+	mu        sync.Mutex
+	durations []string
+	errors    []string
+}
+
+func (m *testMetrics) ObserveDuration(host, method string, statusCode int, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.durations = append(m.durations, method+" "+strconv.Itoa(statusCode))
+}
+
+func (m *testMetrics) CountError(host, method, reason string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.errors = append(m.errors, method+" "+reason)
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/broken":
+			w.WriteHeader(http.StatusBadGateway)
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	metrics := &testMetrics{}
+	client := NewHttpClient(WithMiddleware(MetricsMiddleware(metrics)))
+	client.NewRequest(server.URL).Do(HTTP_METHOD_GET)
+	client.NewRequest(server.URL).SetUri("missing").Do(HTTP_METHOD_DELETE)
+	client.NewRequest(server.URL).SetUri("broken").Do(HTTP_METHOD_POST)
+	client.NewRequest(server.URL).SetUri("slow").SetTimeout(50 * time.Millisecond).Do(HTTP_METHOD_GET)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	client.NewRequest(server.URL).DoContext(ctx, HTTP_METHOD_GET)
+
+	// 按状态码记录耗时，按原因统计错误
+	expected := []string{"GET 200", "DELETE 404", "POST 502", "GET 0", "GET 0"}
+	if len(metrics.durations) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, metrics.durations)
+	}
+	for i := range expected {
+		if metrics.durations[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, metrics.durations)
+		}
+	}
+	expected = []string{"DELETE " + METRIC_ERROR_CLIENT, "POST " + METRIC_ERROR_SERVER, "GET " + METRIC_ERROR_TIMEOUT, "GET " + METRIC_ERROR_CANCELED}
+	if len(metrics.errors) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, metrics.errors)
+	}
+	for i := range expected {
+		if metrics.errors[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, metrics.errors)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package utilsx
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// RoundTripFunc sends a single HTTP request and returns its response.
+type RoundTripFunc func(req *http.Request) (*http.Response, error)
+
+// Middleware wraps a RoundTripFunc to run code around every attempt of a request.
+//
+// Middlewares registered on the HttpClient run before the ones registered on
+// the request, both in registration order.
+type Middleware func(next RoundTripFunc) RoundTripFunc
+
+// WithMiddleware registers middlewares run for every request sent through the client.
+func WithMiddleware(middlewares ...Middleware) HttpClientOption {
+	return func(c *HttpClient) {
+		c.middlewares = append(c.middlewares, middlewares...)
+	}
+}
+
+// Use registers middlewares run for every attempt of the apiRequest, after the client middlewares.
+//
+// Parameters:
+//   - middlewares: the middlewares to register.
+//
+// Returns:
+//   - executableApiRequest: The modified apiRequest struct.
+func (r *ApiRequest) Use(middlewares ...Middleware) ExecutableApiRequest {
+	r.middlewares = append(r.middlewares, middlewares...)
+	return r
+}
+
+// roundTripper chains the client and request middlewares, the request
+// authentication and the response decompression around the client transport.
+func (r *ApiRequest) roundTripper() RoundTripFunc {
+	next := decompressResponse(r.client.httpClient.Do)
+	if r.auth != nil {
+		next = AuthMiddleware(r.auth)(next)
+	}
+	for i := len(r.middlewares) - 1; i >= 0; i-- {
+		next = r.middlewares[i](next)
+	}
+	for i := len(r.client.middlewares) - 1; i >= 0; i-- {
+		next = r.client.middlewares[i](next)
+	}
+	return next
+}
+
+// RequestIDMiddleware sets a random request ID in the given header unless the
+// request already carries one.
+func RequestIDMiddleware(header string) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get(header) == "" {
+				req.Header.Set(header, randomHex(16))
+			}
+			return next(req)
+		}
+	}
+}
+
+// randomHex returns n random bytes encoded as a hex string.
+func randomHex(n int) string {
+	buffer := make([]byte, n)
+	rand.Read(buffer)
+	return hex.EncodeToString(buffer)
+}
@@ -0,0 +1,40 @@
+package utilsx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddlewareChain(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Join(r.Header.Values("X-Trace"), ",")))
+	}))
+	defer server.Close()
+
+	tracer := func(name string) Middleware {
+		return func(next RoundTripFunc) RoundTripFunc {
+			return func(req *http.Request) (*http.Response, error) {
+				req.Header.Add("X-Trace", name)
+				return next(req)
+			}
+		}
+	}
+	client := NewHttpClient(WithMiddleware(tracer("client1"), tracer("client2")))
+
+	// 客户端中间件按添加顺序先于请求中间件执行
+	resp, err := client.NewRequest(server.URL).Use(tracer("request")).Do(HTTP_METHOD_GET)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := resp.Result()
+	if string(data) != "client1,client2,request" {
+		t.Fatalf("unexpected middleware order %q", data)
+	}
+
+	resp, _ = client.NewRequest(server.URL).Use(RequestIDMiddleware("X-Trace")).Do(HTTP_METHOD_GET)
+	if data, _ = resp.Result(); string(data) != "client1,client2" {
+		t.Fatalf("request id must not override existing header, got %q", data)
+	}
+}
@@ -0,0 +1,128 @@
+package utilsx
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// JSONStreamSetting configures a JSONStream.
+type JSONStreamSetting struct {
+	Method      HttpMethod     // HTTP_METHOD_GET when empty
+	MaxLineSize int            // longest accepted line, STREAM_DEFAULT_MAX_LINE_SIZE when 0
+	Decode      []DecodeOption // options decoding every line
+}
+
+// JSONStream decodes a newline-delimited JSON response (NDJSON, JSON Lines)
+// into values of T, one per line. Blank lines are skipped.
+//
+// The request timeout is disabled, use ctx to bound the stream. Lines are
+// read one at a time as the caller asks for them, so a slow consumer slows
+// the reading of the connection down:
+//
+//	logs := NewJSONStream[LogEntry](req, JSONStreamSetting{})
+//	defer logs.Close()
+//	for logs.Next(ctx) {
+//		entry := logs.Item()
+//	}
+//	if err := logs.Err(); err != nil {
+//	}
+type JSONStream[T any] struct {
+	req     ExecutableApiRequest
+	setting JSONStreamSetting
+
+	body  io.ReadCloser
+	lines *bufio.Scanner
+	line  int
+	item  T
+	done  bool
+	err   error
+}
+
+// NewJSONStream creates a new JSONStream.
+//
+// Parameters:
+//   - req: the request of the stream.
+//   - setting: the JSON stream setting.
+//
+// Returns:
+//   - *JSONStream[T]: the created JSON stream, sent by the first Next call.
+func NewJSONStream[T any](req ExecutableApiRequest, setting JSONStreamSetting) *JSONStream[T] {
+	if setting.Method == "" {
+		setting.Method = HTTP_METHOD_GET
+	}
+	if setting.MaxLineSize <= 0 {
+		setting.MaxLineSize = STREAM_DEFAULT_MAX_LINE_SIZE
+	}
+	req.SetHeader("Accept", "application/x-ndjson, application/jsonl, application/json").SetTimeout(0)
+	return &JSONStream[T]{req: req, setting: setting}
+}
+
+// Next decodes the next value, sending the request on the first call.
+//
+// It returns false at the end of the response or on an error, which Err then returns.
+func (s *JSONStream[T]) Next(ctx context.Context) bool {
+	if s.done || s.err != nil {
+		return false
+	}
+	if s.lines == nil {
+		stream, err := s.req.DoStream(ctx, s.setting.Method)
+		if err != nil {
+			s.err = err
+			return false
+		}
+		s.body = stream.Body
+		s.lines = newLineScanner(stream.Body, s.setting.MaxLineSize)
+	}
+	for s.lines.Scan() {
+		s.line++
+		line := s.lines.Bytes()
+		if strings.TrimSpace(string(line)) == "" {
+			continue
+		}
+		item, err := DecodeJSON[T](line, s.setting.Decode...)
+		if err != nil {
+			s.err = fmt.Errorf("utilsx: ndjson line %d: %w", s.line, err)
+			s.Close()
+			return false
+		}
+		s.item = item
+		return true
+	}
+	if err := s.lines.Err(); err != nil {
+		s.err = contextError(ctx, err)
+	}
+	s.Close()
+	return false
+}
+
+// Item returns the current value.
+func (s *JSONStream[T]) Item() T {
+	return s.item
+}
+
+// Err returns the error that stopped the stream.
+func (s *JSONStream[T]) Err() error {
+	return s.err
+}
+
+// Close closes the response body, Next then returns false.
+func (s *JSONStream[T]) Close() error {
+	s.done = true
+	if s.body != nil {
+		s.body.Close()
+		s.body = nil
+	}
+	return nil
+}
+
+// Chan returns a channel receiving the values, closed at the end of the
+// stream, after which Err returns the error that stopped it.
+//
+// The channel is unbuffered, so lines are only read as fast as values are
+// received. The stream is closed when ctx is done.
+func (s *JSONStream[T]) Chan(ctx context.Context) <-chan T {
+	return streamChan(ctx, s.Next, s.Item, s.Close, &s.err)
+}
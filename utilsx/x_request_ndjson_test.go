@@ -0,0 +1,72 @@
+package utilsx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type testLogEntry struct {
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
+
+func newTestNDJSONServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		switch r.URL.Path {
+		case "/invalid":
+			fmt.Fprint(w, "{\"level\":\"info\"}\n\n{\"level\":\n")
+		default:
+			// 空行被跳过，兼容CRLF换行和无换行结尾
+			for i := 0; i < 5; i++ {
+				fmt.Fprintf(w, "{\"level\":\"info\",\"message\":\"m%d\"}\r\n\n", i)
+				w.(http.Flusher).Flush()
+			}
+			fmt.Fprint(w, `{"level":"error","message":"last"}`)
+		}
+	}))
+}
+
+func TestJSONStream(t *testing.T) {
+	server := newTestNDJSONServer()
+	defer server.Close()
+	client := NewHttpClient()
+
+	logs := NewJSONStream[testLogEntry](client.NewRequest(server.URL), JSONStreamSetting{})
+	var messages []string
+	for logs.Next(context.Background()) {
+		messages = append(messages, logs.Item().Message)
+	}
+	if logs.Err() != nil || strings.Join(messages, ",") != "m0,m1,m2,m3,m4,last" {
+		t.Fatalf("unexpected items %v, %v", messages, logs.Err())
+	}
+
+	// 解码错误包含行号
+	logs = NewJSONStream[testLogEntry](client.NewRequest(server.URL).SetUri("invalid"), JSONStreamSetting{})
+	count := 0
+	for logs.Next(context.Background()) {
+		count++
+	}
+	if count != 1 || logs.Err() == nil || !strings.Contains(logs.Err().Error(), "line 3") {
+		t.Fatalf("expected decode error on line 3, got %d items and %v", count, logs.Err())
+	}
+
+	// 使用channel接收，未知字段报错
+	logs = NewJSONStream[testLogEntry](client.NewRequest(server.URL), JSONStreamSetting{})
+	count = 0
+	for range logs.Chan(context.Background()) {
+		count++
+	}
+	if count != 6 || logs.Err() != nil {
+		t.Fatalf("unexpected channel result %d, %v", count, logs.Err())
+	}
+	strict := NewJSONStream[struct{ Level string }](client.NewRequest(server.URL),
+		JSONStreamSetting{Decode: []DecodeOption{WithDisallowUnknownFields()}})
+	if strict.Next(context.Background()) || strict.Err() == nil {
+		t.Fatal("expected unknown field error")
+	}
+}
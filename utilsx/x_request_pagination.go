@@ -0,0 +1,360 @@
+package utilsx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// PaginationStrategy moves a request from one page to the next.
+//
+// Start prepares the request of the first page. Next prepares the request of
+// the page following page and returns false when page was the last one.
+type PaginationStrategy interface {
+	Start(req ExecutableApiRequest)
+	Next(req ExecutableApiRequest, page *Page) (bool, error)
+}
+
+// Page is a fetched page, passed to PaginationStrategy.Next.
+type Page struct {
+	Number     int         // 1 for the first page
+	URL        string      // URL the page was fetched from
+	Header     http.Header // response headers
+	Body       []byte      // response body
+	Items      int         // number of items on the page
+	TotalItems int         // number of items on this page and the previous ones
+}
+
+// LinkPagination follows the rel="next" URL of the Link response header (RFC 8288).
+type LinkPagination struct{}
+
+// CursorPagination sends the cursor found in the response body in a query parameter.
+//
+// Pagination stops when the cursor is missing, null or empty.
+type CursorPagination struct {
+	Param string // query parameter of the cursor, such as "cursor"
+	Path  string // dot separated path of the next cursor in the JSON body, such as "meta.next_cursor"
+}
+
+// PageNumberPagination sends an incrementing page number in a query parameter.
+//
+// Pagination stops on an empty page, or on a page shorter than Size when Size is set.
+type PageNumberPagination struct {
+	Param     string // query parameter of the page number, such as "page"
+	First     int    // number of the first page, usually 0 or 1
+	SizeParam string // optional query parameter of the page size
+	Size      int    // page size sent in SizeParam
+}
+
+// OffsetPagination sends the number of items already read in a query parameter.
+//
+// Pagination stops on an empty page, or on a page shorter than Limit when Limit is set.
+type OffsetPagination struct {
+	OffsetParam string // query parameter of the offset, such as "offset"
+	LimitParam  string // optional query parameter of the page size
+	Limit       int    // page size sent in LimitParam
+}
+
+// PaginationSetting configures a Paginator.
+type PaginationSetting struct {
+	Method    HttpMethod         // HTTP_METHOD_GET when empty
+	Strategy  PaginationStrategy // how to move to the next page
+	ItemsPath string             // dot separated path of the items array in the JSON body, the body itself when empty
+	MaxPages  int                // stop after this many pages, 0 means no limit
+	MaxItems  int                // stop after this many items, 0 means no limit
+}
+
+// Paginator lazily iterates over the items of a paginated API.
+//
+// Pages are fetched by sending req again with the query parameters or URL set
+// by the strategy, so req must not be used concurrently:
+//
+//	pages := NewPaginator[Region](req, PaginationSetting{Strategy: LinkPagination{}})
+//	for pages.Next(ctx) {
+//		region := pages.Item()
+//	}
+//	if err := pages.Err(); err != nil {
+//	}
+type Paginator[T any] struct {
+	req     ExecutableApiRequest
+	setting PaginationSetting
+
+	items []T
+	index int
+	item  T
+	page  *Page
+	count int
+	done  bool
+	err   error
+}
+
+// NewPaginator creates a new Paginator.
+//
+// Parameters:
+//   - req: the request of the first page.
+//   - setting: the pagination setting.
+//
+// Returns:
+//   - *Paginator[T]: the created paginator.
+func NewPaginator[T any](req ExecutableApiRequest, setting PaginationSetting) *Paginator[T] {
+	if setting.Method == "" {
+		setting.Method = HTTP_METHOD_GET
+	}
+	return &Paginator[T]{req: req, setting: setting}
+}
+
+// Next advances to the next item, fetching the next page when needed.
+//
+// It returns false at the end of the items, when a guard is reached or on an
+// error, which Err then returns.
+func (p *Paginator[T]) Next(ctx context.Context) bool {
+	if p.setting.MaxItems > 0 && p.count >= p.setting.MaxItems {
+		return false
+	}
+	for p.index >= len(p.items) {
+		if p.done || p.err != nil {
+			return false
+		}
+		if p.setting.MaxPages > 0 && p.page != nil && p.page.Number >= p.setting.MaxPages {
+			p.done = true
+			return false
+		}
+		p.err = p.fetch(ctx)
+	}
+	p.item = p.items[p.index]
+	p.index++
+	p.count++
+	return true
+}
+
+// Item returns the current item.
+func (p *Paginator[T]) Item() T {
+	return p.item
+}
+
+// Page returns the last fetched page, nil before the first one.
+func (p *Paginator[T]) Page() *Page {
+	return p.page
+}
+
+// Err returns the error that stopped the iteration.
+func (p *Paginator[T]) Err() error {
+	return p.err
+}
+
+// All reads the remaining items.
+//
+// Parameters:
+//   - ctx: the context of the page requests.
+//
+// Returns:
+//   - []T: the items read before the end or the error.
+//   - error: the error that stopped the iteration.
+func (p *Paginator[T]) All(ctx context.Context) ([]T, error) {
+	var items []T
+	for p.Next(ctx) {
+		items = append(items, p.Item())
+	}
+	return items, p.Err()
+}
+
+// fetch requests the next page and prepares the request of the following one.
+func (p *Paginator[T]) fetch(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return contextError(ctx, err)
+	}
+	number := 1
+	if p.page == nil {
+		p.setting.Strategy.Start(p.req)
+	} else {
+		number = p.page.Number + 1
+	}
+
+	resp, err := p.req.DoContext(ctx, p.setting.Method)
+	if err != nil {
+		return err
+	}
+	body, _ := resp.Result()
+	if !isSuccessStatus(resp.StatusCode()) {
+		return fmt.Errorf("utilsx: unexpected status code %d", resp.StatusCode())
+	}
+	raw, ok, err := jsonLookup(body, p.setting.ItemsPath)
+	if err != nil {
+		return err
+	}
+	p.items, p.index = nil, 0
+	if ok {
+		if err = json.Unmarshal(raw, &p.items); err != nil {
+			return err
+		}
+	}
+
+	p.page = &Page{
+		Number:     number,
+		URL:        requestUrl(p.req),
+		Header:     resp.Header(),
+		Body:       body,
+		Items:      len(p.items),
+		TotalItems: p.count + len(p.items),
+	}
+	more, err := p.setting.Strategy.Next(p.req, p.page)
+	p.done = !more
+	return err
+}
+
+// Start implements PaginationStrategy.
+func (LinkPagination) Start(ExecutableApiRequest) {}
+
+// Next implements PaginationStrategy.
+func (LinkPagination) Next(req ExecutableApiRequest, page *Page) (bool, error) {
+	next := nextLink(page.Header)
+	if next == "" {
+		return false, nil
+	}
+	apiRequest, ok := req.(*ApiRequest)
+	if !ok {
+		return false, errors.New("utilsx: link pagination needs a request created by NewHttpRequest")
+	}
+	base, err := url.Parse(page.URL)
+	if err != nil {
+		return false, err
+	}
+	nextUrl, err := base.Parse(next)
+	if err != nil {
+		return false, err
+	}
+	apiRequest.setUrl(nextUrl)
+	return true, nil
+}
+
+// Start implements PaginationStrategy.
+func (CursorPagination) Start(ExecutableApiRequest) {}
+
+// Next implements PaginationStrategy.
+func (s CursorPagination) Next(req ExecutableApiRequest, page *Page) (bool, error) {
+	raw, ok, err := jsonLookup(page.Body, s.Path)
+	if err != nil || !ok {
+		return false, err
+	}
+	var cursor interface{}
+	if err = json.Unmarshal(raw, &cursor); err != nil {
+		return false, err
+	}
+	var value string
+	switch cursor := cursor.(type) {
+	case nil:
+		return false, nil
+	case string:
+		value = cursor
+	default:
+		value = string(bytes.TrimSpace(raw))
+	}
+	if value == "" {
+		return false, nil
+	}
+	req.SetQueryParam(s.Param, value)
+	return true, nil
+}
+
+// Start implements PaginationStrategy.
+func (s PageNumberPagination) Start(req ExecutableApiRequest) {
+	req.SetQueryParam(s.Param, strconv.Itoa(s.First))
+	if s.SizeParam != "" && s.Size > 0 {
+		req.SetQueryParam(s.SizeParam, strconv.Itoa(s.Size))
+	}
+}
+
+// Next implements PaginationStrategy.
+func (s PageNumberPagination) Next(req ExecutableApiRequest, page *Page) (bool, error) {
+	if page.Items == 0 || (s.Size > 0 && page.Items < s.Size) {
+		return false, nil
+	}
+	req.SetQueryParam(s.Param, strconv.Itoa(s.First+page.Number))
+	return true, nil
+}
+
+// Start implements PaginationStrategy.
+func (s OffsetPagination) Start(req ExecutableApiRequest) {
+	req.SetQueryParam(s.OffsetParam, "0")
+	if s.LimitParam != "" && s.Limit > 0 {
+		req.SetQueryParam(s.LimitParam, strconv.Itoa(s.Limit))
+	}
+}
+
+// Next implements PaginationStrategy.
+func (s OffsetPagination) Next(req ExecutableApiRequest, page *Page) (bool, error) {
+	if page.Items == 0 || (s.Limit > 0 && page.Items < s.Limit) {
+		return false, nil
+	}
+	req.SetQueryParam(s.OffsetParam, strconv.Itoa(page.TotalItems))
+	return true, nil
+}
+
+// setUrl points the request to an absolute URL, replacing its path and query.
+func (r *ApiRequest) setUrl(u *url.URL) {
+	r.schema = u.Scheme
+	r.serviceAddress = u.String()
+	r.uri = ""
+	r.query = u.Query()
+}
+
+// requestUrl returns the URL of a request created by NewHttpRequest.
+func requestUrl(req ExecutableApiRequest) string {
+	if apiRequest, ok := req.(*ApiRequest); ok {
+		return apiRequest.GetUrl()
+	}
+	return ""
+}
+
+// nextLink returns the rel="next" target of the Link header.
+func nextLink(header http.Header) string {
+	for _, value := range header.Values("Link") {
+		for _, link := range strings.Split(value, ",") {
+			target, params, _ := strings.Cut(link, ";")
+			target = strings.TrimSpace(target)
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			for _, param := range strings.Split(params, ";") {
+				name, rel, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if !strings.EqualFold(name, "rel") {
+					continue
+				}
+				for _, relation := range strings.Fields(strings.Trim(rel, `"`)) {
+					if strings.EqualFold(relation, "next") {
+						return target[1 : len(target)-1]
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// jsonLookup returns the JSON value at a dot separated path of body.
+//
+// It returns the raw value, false when the path does not exist, and the
+// error of a body that is not JSON.
+func jsonLookup(body []byte, path string) (json.RawMessage, bool, error) {
+	raw := json.RawMessage(body)
+	if path == "" {
+		return raw, len(bytes.TrimSpace(body)) > 0, nil
+	}
+	for _, key := range strings.Split(path, ".") {
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &object); err != nil {
+			return nil, false, err
+		}
+		var ok bool
+		if raw, ok = object[key]; !ok {
+			return nil, false, nil
+		}
+	}
+	return raw, true, nil
+}
@@ -0,0 +1,31 @@
+//go:build go1.23
+
+package utilsx
+
+import (
+	"context"
+	"iter"
+)
+
+// Items returns an iterator over the remaining items, for use with range.
+//
+// The iteration stops after yielding a non-nil error:
+//
+//	for region, err := range pages.Items(ctx) {
+//		if err != nil {
+//			return err
+//		}
+//	}
+func (p *Paginator[T]) Items(ctx context.Context) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for p.Next(ctx) {
+			if !yield(p.Item(), nil) {
+				return
+			}
+		}
+		if err := p.Err(); err != nil {
+			var zero T
+			yield(zero, err)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+//go:build go1.23
+
+package utilsx
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPaginatorItems(t *testing.T) {
+	var requests int32
+	server := newPaginatedServer(&requests)
+	defer server.Close()
+
+	// 使用range遍历分页数据，提前break时不再请求后续页
+	pages := NewPaginator[testPageItem](NewHttpClient().NewRequest(server.URL).SetUri("offset"),
+		PaginationSetting{Strategy: OffsetPagination{OffsetParam: "offset", LimitParam: "limit", Limit: 3}})
+	var ids []int
+	for item, err := range pages.Items(context.Background()) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, item.ID)
+		if len(ids) == 5 {
+			break
+		}
+	}
+	if len(ids) != 5 || ids[4] != 4 {
+		t.Fatalf("unexpected items %v", ids)
+	}
+	if requests != 2 {
+		t.Fatalf("expected 2 page requests, got %d", requests)
+	}
+}
@@ -0,0 +1,146 @@
+package utilsx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+type testPageItem struct {
+	ID int `json:"id"`
+}
+
+// newPaginatedServer 返回共10条数据的分页测试服务，记录请求的页数
+func newPaginatedServer(requests *int32) *httptest.Server {
+	const total = 10
+	items := func(from, to int) []testPageItem {
+		var page []testPageItem
+		for id := from; id < to && id < total; id++ {
+			page = append(page, testPageItem{ID: id})
+		}
+		return page
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		query := r.URL.Query()
+		switch r.URL.Path {
+		case "/link":
+			page, _ := strconv.Atoi(query.Get("page"))
+			if (page+1)*3 < total {
+				w.Header().Set("Link", fmt.Sprintf(`</link?page=%d>; rel="next", </link?page=0>; rel="first"`, page+1))
+			}
+			json.NewEncoder(w).Encode(items(page*3, page*3+3))
+		case "/cursor":
+			from, _ := strconv.Atoi(query.Get("cursor"))
+			body := map[string]interface{}{"data": items(from, from+4), "meta": map[string]interface{}{"next": nil}}
+			if from+4 < total {
+				body["meta"] = map[string]interface{}{"next": strconv.Itoa(from + 4)}
+			}
+			json.NewEncoder(w).Encode(body)
+		case "/page":
+			page, _ := strconv.Atoi(query.Get("page"))
+			size, _ := strconv.Atoi(query.Get("size"))
+			json.NewEncoder(w).Encode(map[string]interface{}{"items": items((page-1)*size, page*size)})
+		case "/offset":
+			offset, _ := strconv.Atoi(query.Get("offset"))
+			json.NewEncoder(w).Encode(items(offset, offset+3))
+		}
+	}))
+}
+
+func TestPaginatorStrategies(t *testing.T) {
+	var requests int32
+	server := newPaginatedServer(&requests)
+	defer server.Close()
+
+	cases := []struct {
+		uri     string
+		setting PaginationSetting
+		pages   int32
+	}{
+		{"link", PaginationSetting{Strategy: LinkPagination{}}, 4},
+		{"cursor", PaginationSetting{Strategy: CursorPagination{Param: "cursor", Path: "meta.next"}, ItemsPath: "data"}, 3},
+		{"page", PaginationSetting{Strategy: PageNumberPagination{Param: "page", First: 1, SizeParam: "size", Size: 5}, ItemsPath: "items"}, 3},
+		{"offset", PaginationSetting{Strategy: OffsetPagination{OffsetParam: "offset", LimitParam: "limit", Limit: 3}}, 4},
+	}
+	for _, c := range cases {
+		t.Run(c.uri, func(t *testing.T) {
+			atomic.StoreInt32(&requests, 0)
+			// 三种分页方式都能按顺序读取全部数据
+			pages := NewPaginator[testPageItem](NewHttpClient().NewRequest(server.URL).SetUri(c.uri), c.setting)
+			items, err := pages.All(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(items) != 10 {
+				t.Fatalf("expected 10 items, got %d", len(items))
+			}
+			for i, item := range items {
+				if item.ID != i {
+					t.Fatalf("item %d out of order: %d", i, item.ID)
+				}
+			}
+			if got := atomic.LoadInt32(&requests); got != c.pages {
+				t.Fatalf("expected %d page requests, got %d", c.pages, got)
+			}
+		})
+	}
+}
+
+func TestPaginatorGuards(t *testing.T) {
+	var requests int32
+	server := newPaginatedServer(&requests)
+	defer server.Close()
+
+	// 限制最大条数时按需加载，不请求多余的页
+	pages := NewPaginator[testPageItem](NewHttpClient().NewRequest(server.URL).SetUri("link"),
+		PaginationSetting{Strategy: LinkPagination{}, MaxItems: 4})
+	items, err := pages.All(context.Background())
+	if err != nil || len(items) != 4 {
+		t.Fatalf("expected 4 items, got %d %v", len(items), err)
+	}
+	if atomic.LoadInt32(&requests) != 2 {
+		t.Fatalf("expected 2 page requests, got %d", requests)
+	}
+
+	// 限制最大页数
+	pages = NewPaginator[testPageItem](NewHttpClient().NewRequest(server.URL).SetUri("link"),
+		PaginationSetting{Strategy: LinkPagination{}, MaxPages: 2})
+	items, err = pages.All(context.Background())
+	if err != nil || len(items) != 6 || pages.Page().Number != 2 {
+		t.Fatalf("expected 6 items of 2 pages, got %d %v", len(items), err)
+	}
+
+	// context取消后停止迭代
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pages = NewPaginator[testPageItem](NewHttpClient().NewRequest(server.URL).SetUri("offset"),
+		PaginationSetting{Strategy: OffsetPagination{OffsetParam: "offset"}})
+	for pages.Next(ctx) {
+		cancel()
+	}
+	if !errors.Is(pages.Err(), context.Canceled) {
+		t.Fatalf("expected canceled error, got %v", pages.Err())
+	}
+}
+
+func TestPaginatorHttpError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	pages := NewPaginator[testPageItem](NewHttpClient().NewRequest(server.URL), PaginationSetting{Strategy: LinkPagination{}})
+	if pages.Next(context.Background()) {
+		t.Fatal("expected no item")
+	}
+	if !IsStatus(pages.Err(), http.StatusBadGateway) {
+		t.Fatalf("expected http error, got %v", pages.Err())
+	}
+}
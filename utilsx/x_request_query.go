@@ -0,0 +1,282 @@
+package utilsx
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// queryTag is the parsed url tag of a struct field.
+type queryTag struct {
+	name      string
+	omitEmpty bool
+	comma     bool   // join slice values with commas instead of repeating the key
+	unix      bool   // encode times as Unix seconds
+	layout    string // time layout from the layout tag, time.RFC3339 when empty
+}
+
+// SetQueryStruct sets the query parameters encoded from a tagged struct, see EncodeQuery.
+//
+// The parameters replace the previous values of the same keys. An encoding
+// error is returned when the apiRequest is sent, unless a later call succeeds.
+//
+// Parameters:
+//   - value: the struct, or pointer to struct, to encode.
+//
+// Returns:
+//   - executableApiRequest: The modified apiRequest struct.
+func (r *ApiRequest) SetQueryStruct(value interface{}) ExecutableApiRequest {
+	values, err := EncodeQuery(value)
+	if err != nil {
+		r.queryErr = err
+		return r
+	}
+	r.queryErr = nil
+	for key, list := range values {
+		r.query[key] = list
+	}
+	return r
+}
+
+// EncodeQuery encodes a struct into query parameters.
+//
+// Fields are named by their url tag, such as `url:"page_size,omitempty"`, or
+// by their Go name without tag, and a "-" tag skips the field. The options are:
+//   - omitempty: skip zero values and empty slices.
+//   - comma: join slice values with commas, slices repeat the key by default.
+//   - unix: encode a time.Time as Unix seconds, otherwise it uses the layout
+//     tag, such as `layout:"2006-01-02"`, or time.RFC3339.
+//
+// Nested structs and maps are encoded with bracketed keys, such as
+// filter[status], and slices of structs with their index, such as items[0][id].
+// Fields of embedded structs are encoded as fields of the outer struct.
+// Types implementing encoding.TextMarshaler are encoded with MarshalText.
+//
+// Parameters:
+//   - value: the struct, or pointer to struct, to encode, nil gives no parameters.
+//
+// Returns:
+//   - url.Values: the encoded query parameters.
+//   - error: the error of an unsupported field type.
+func EncodeQuery(value interface{}) (url.Values, error) {
+	values := make(url.Values)
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return values, nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return values, nil
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("utilsx: query value must be a struct, got %s", v.Type())
+	}
+	return values, encodeQueryStruct(values, "", v)
+}
+
+// encodeQueryStruct encodes the fields of v, prefixing their names with prefix.
+func encodeQueryStruct(values url.Values, prefix string, v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		// fields of an unexported embedded struct are still promoted
+		if !field.IsExported() && !(field.Anonymous && field.Type.Kind() == reflect.Struct) {
+			continue
+		}
+		tag := parseQueryTag(field)
+		if tag.name == "-" {
+			continue
+		}
+		fieldValue := v.Field(i)
+		if field.Anonymous && field.Tag.Get("url") == "" {
+			embedded, ok := indirectQueryValue(fieldValue)
+			if !ok {
+				continue
+			}
+			if embedded.Kind() == reflect.Struct && !isQueryScalar(embedded.Type()) {
+				if err := encodeQueryStruct(values, prefix, embedded); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		name := tag.name
+		if prefix != "" {
+			name = prefix + "[" + name + "]"
+		}
+		if err := encodeQueryValue(values, name, fieldValue, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeQueryValue encodes a field value under key.
+func encodeQueryValue(values url.Values, key string, v reflect.Value, tag queryTag) error {
+	v, ok := indirectQueryValue(v)
+	if !ok {
+		return nil
+	}
+	if tag.omitEmpty && isEmptyQueryValue(v) {
+		return nil
+	}
+
+	switch {
+	case isQueryScalar(v.Type()):
+		value, err := formatQueryScalar(v, tag)
+		if err != nil {
+			return err
+		}
+		values.Add(key, value)
+		return nil
+	case v.Kind() == reflect.Struct:
+		return encodeQueryStruct(values, key, v)
+	case v.Kind() == reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("utilsx: unsupported query map key type %s", v.Type().Key())
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := encodeQueryValue(values, key+"["+iter.Key().String()+"]", iter.Value(), queryTag{}); err != nil {
+				return err
+			}
+		}
+		return nil
+	case v.Kind() == reflect.Slice || v.Kind() == reflect.Array:
+		var joined []string
+		for i := 0; i < v.Len(); i++ {
+			item, ok := indirectQueryValue(v.Index(i))
+			if !ok {
+				continue
+			}
+			if !isQueryScalar(item.Type()) {
+				if err := encodeQueryValue(values, key+"["+strconv.Itoa(i)+"]", item, queryTag{}); err != nil {
+					return err
+				}
+				continue
+			}
+			value, err := formatQueryScalar(item, tag)
+			if err != nil {
+				return err
+			}
+			if tag.comma {
+				joined = append(joined, value)
+			} else {
+				values.Add(key, value)
+			}
+		}
+		if tag.comma && len(joined) > 0 {
+			values.Add(key, strings.Join(joined, ","))
+		}
+		return nil
+	}
+	return fmt.Errorf("utilsx: unsupported query value type %s", v.Type())
+}
+
+// formatQueryScalar formats a value of a type accepted by isQueryScalar.
+func formatQueryScalar(v reflect.Value, tag queryTag) (string, error) {
+	if v.Type() == timeType {
+		t := v.Interface().(time.Time)
+		if tag.unix {
+			return strconv.FormatInt(t.Unix(), 10), nil
+		}
+		if tag.layout != "" {
+			return t.Format(tag.layout), nil
+		}
+		return t.Format(time.RFC3339), nil
+	}
+	if marshaler, ok := v.Interface().(encoding.TextMarshaler); ok {
+		text, err := marshaler.MarshalText()
+		return string(text), err
+	}
+	if reflect.PointerTo(v.Type()).Implements(textMarshalerType) {
+		pointer := reflect.New(v.Type())
+		pointer.Elem().Set(v)
+		text, err := pointer.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(text), err
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 32), nil
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64), nil
+	}
+	return "", fmt.Errorf("utilsx: unsupported query value type %s", v.Type())
+}
+
+// isQueryScalar reports whether values of t are encoded as a single parameter.
+func isQueryScalar(t reflect.Type) bool {
+	if t == timeType || t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// indirectQueryValue dereferences pointers and interfaces, and returns false for nil values.
+func indirectQueryValue(v reflect.Value) (reflect.Value, bool) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return v, false
+		}
+		v = v.Elem()
+	}
+	return v, v.IsValid()
+}
+
+// isEmptyQueryValue reports whether v is skipped by the omitempty option.
+func isEmptyQueryValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+// parseQueryTag parses the url and layout tags of a struct field.
+func parseQueryTag(field reflect.StructField) queryTag {
+	name, options, _ := strings.Cut(field.Tag.Get("url"), ",")
+	tag := queryTag{name: name, layout: field.Tag.Get("layout")}
+	if tag.name == "" {
+		tag.name = field.Name
+	}
+	for _, option := range strings.Split(options, ",") {
+		switch option {
+		case "omitempty":
+			tag.omitEmpty = true
+		case "comma":
+			tag.comma = true
+		case "unix":
+			tag.unix = true
+		}
+	}
+	return tag
+}
@@ -0,0 +1,122 @@
+package utilsx
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testQueryPaging struct {
+	Page int `url:"page,omitempty"`
+	Size int `url:"size"`
+}
+
+type testQueryFilter struct {
+	Status []string `url:"status,comma"`
+	Owner  *string  `url:"owner,omitempty"`
+}
+
+type testQuery struct {
+	testQueryPaging
+	IDs      []int             `url:"ids"`
+	Keyword  string            `url:"q,omitempty"`
+	Since    time.Time         `url:"since"`
+	Day      time.Time         `url:"day" layout:"2006-01-02"`
+	Until    time.Time         `url:"until,unix"`
+	Missing  *time.Time        `url:"missing"`
+	Filter   testQueryFilter   `url:"filter"`
+	Labels   map[string]string `url:"labels,omitempty"`
+	Items    []testPageItem    `url:"items"`
+	IP       net.IP            `url:"ip"`
+	Ratio    float64           `url:"ratio"`
+	Enabled  bool              `url:"enabled"`
+	Internal string            `url:"-"`
+	Default  string
+	hidden   string
+}
+
+func TestEncodeQuery(t *testing.T) {
+	moment := time.Date(2024, 3, 1, 8, 30, 0, 0, time.UTC)
+	owner := "bob"
+	values, err := EncodeQuery(&testQuery{
+		testQueryPaging: testQueryPaging{Size: 20},
+		IDs:             []int{1, 2},
+		Since:           moment,
+		Day:             moment,
+		Until:           moment,
+		Filter:          testQueryFilter{Status: []string{"open", "closed"}, Owner: &owner},
+		Labels:          map[string]string{"env": "prod"},
+		Items:           []testPageItem{{ID: 7}},
+		IP:              net.ParseIP("10.0.0.1"),
+		Ratio:           0.5,
+		Internal:        "secret",
+		Default:         "d",
+		hidden:          "h",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 切片重复参数名，时间按格式编码，嵌套结构体使用中括号前缀
+	expected := url.Values{
+		"size":           {"20"},
+		"ids":            {"1", "2"},
+		"since":          {"2024-03-01T08:30:00Z"},
+		"day":            {"2024-03-01"},
+		"until":          {"1709281800"},
+		"filter[status]": {"open,closed"},
+		"filter[owner]":  {"bob"},
+		"labels[env]":    {"prod"},
+		"items[0][ID]":   {"7"},
+		"ip":             {"10.0.0.1"},
+		"ratio":          {"0.5"},
+		"enabled":        {"false"},
+		"Default":        {"d"},
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Fatalf("expected %v, got %v", expected, values)
+	}
+
+	if values, err = EncodeQuery(nil); err != nil || len(values) != 0 {
+		t.Fatalf("expected no parameters, got %v %v", values, err)
+	}
+	for _, value := range []interface{}{"text", struct{ C chan int }{}, struct {
+		M map[int]string
+	}{M: map[int]string{1: "a"}}} {
+		if _, err = EncodeQuery(value); err == nil {
+			t.Fatalf("expected error for %T", value)
+		}
+	}
+}
+
+func TestSetQueryStruct(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.RawQuery))
+	}))
+	defer server.Close()
+
+	// 结构体参数覆盖同名参数，保留其它参数
+	resp, err := NewHttpClient().NewRequest(server.URL + "?size=5&sort=name").
+		SetQueryStruct(testQueryPaging{Page: 2, Size: 10}).Do(HTTP_METHOD_GET)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result, _ := resp.Result(); string(result) != "page=2&size=10&sort=name" {
+		t.Fatalf("unexpected query %s", result)
+	}
+
+	// 编码错误在发送时返回
+	if _, err = NewHttpClient().NewRequest(server.URL).SetQueryStruct(42).Do(HTTP_METHOD_GET); err == nil {
+		t.Fatal("expected encoding error")
+	}
+	// 之后成功的调用清除之前的编码错误
+	resp, err = NewHttpClient().NewRequest(server.URL).SetQueryStruct(42).
+		SetQueryStruct(testQueryPaging{Page: 3}).Do(HTTP_METHOD_GET)
+	if result, _ := resp.Result(); err != nil || string(result) != "page=3&size=0" {
+		t.Fatalf("expected the error to be cleared, got %s %v", result, err)
+	}
+}
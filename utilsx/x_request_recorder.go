@@ -0,0 +1,329 @@
+package utilsx
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+type RecorderMode int
+
+const (
+	RECORDER_MODE_REPLAY           RecorderMode = iota // replay the cassette, fail on unmatched requests
+	RECORDER_MODE_RECORD                               // send every request and record a new cassette
+	RECORDER_MODE_REPLAY_OR_RECORD                     // replay matched requests, send and record the others
+)
+
+// ErrInteractionNotFound is returned in replay mode for a request missing from the cassette.
+var ErrInteractionNotFound = errors.New("utilsx: no recorded interaction matches the request")
+
+// RecorderSetting configures a Recorder.
+type RecorderSetting struct {
+	Mode         RecorderMode
+	CassettePath string // cassette file, YAML for .yaml and .yml extensions, JSON otherwise
+
+	RedactHeaders     []string // headers redacted in the cassette, defaultRedactHeaders when nil
+	RedactQueryParams []string // query parameters redacted in the cassette, such as API keys
+	RedactJSONPaths   []string // JSON body values redacted in the cassette, see WithLogRedactJSONPaths
+
+	Transport http.RoundTripper // transport sending the recorded requests, http.DefaultTransport when nil
+}
+
+// Recorder is an http.RoundTripper recording real interactions into a
+// cassette file and replaying them without network access.
+//
+// Attach it to a client with WithTransport, and to NewHttpRequest with
+// SetDefaultHttpClient. Requests match a recorded interaction on method, URL
+// and body, after redaction.
+type Recorder struct {
+	setting      RecorderSetting
+	redactPaths  [][]string
+	mu           sync.Mutex
+	interactions []*Interaction
+	used         []bool
+}
+
+// Interaction is a recorded request and its response.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request" yaml:"request"`
+	Response RecordedResponse `json:"response" yaml:"response"`
+}
+
+type RecordedRequest struct {
+	Method     string      `json:"method" yaml:"method"`
+	URL        string      `json:"url" yaml:"url"`
+	Header     http.Header `json:"header,omitempty" yaml:"header,omitempty"`
+	Body       string      `json:"body,omitempty" yaml:"body,omitempty"`
+	BodyBase64 bool        `json:"body_base64,omitempty" yaml:"body_base64,omitempty"`
+}
+
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code" yaml:"status_code"`
+	Status     string      `json:"status" yaml:"status"`
+	Header     http.Header `json:"header,omitempty" yaml:"header,omitempty"`
+	Body       string      `json:"body,omitempty" yaml:"body,omitempty"`
+	BodyBase64 bool        `json:"body_base64,omitempty" yaml:"body_base64,omitempty"`
+}
+
+type cassette struct {
+	Interactions []*Interaction `json:"interactions" yaml:"interactions"`
+}
+
+// NewRecorder creates a new Recorder.
+//
+// The cassette is loaded in the replay modes, and must exist in RECORDER_MODE_REPLAY.
+//
+// Parameters:
+//   - setting: the recorder setting.
+//
+// Returns:
+//   - *Recorder: the created recorder.
+//   - error: the cassette loading error.
+func NewRecorder(setting RecorderSetting) (*Recorder, error) {
+	if setting.RedactHeaders == nil {
+		setting.RedactHeaders = defaultRedactHeaders
+	}
+	if setting.Transport == nil {
+		setting.Transport = http.DefaultTransport
+	}
+	recorder := &Recorder{setting: setting}
+	for _, path := range setting.RedactJSONPaths {
+		recorder.redactPaths = append(recorder.redactPaths, strings.Split(path, "."))
+	}
+	if setting.Mode == RECORDER_MODE_RECORD {
+		return recorder, nil
+	}
+
+	data, err := os.ReadFile(setting.CassettePath)
+	if err != nil {
+		if os.IsNotExist(err) && setting.Mode == RECORDER_MODE_REPLAY_OR_RECORD {
+			return recorder, nil
+		}
+		return nil, err
+	}
+	var loaded cassette
+	if recorder.isYAML() {
+		err = yaml.Unmarshal(data, &loaded)
+	} else {
+		err = json.Unmarshal(data, &loaded)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("utilsx: parse cassette %s: %w", setting.CassettePath, err)
+	}
+	recorder.interactions = loaded.Interactions
+	recorder.used = make([]bool, len(loaded.Interactions))
+	return recorder, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (rec *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	recorded := rec.recordRequest(req, body)
+
+	if rec.setting.Mode != RECORDER_MODE_RECORD {
+		if interaction := rec.match(recorded); interaction != nil {
+			return interaction.Response.toResponse(req)
+		}
+		if rec.setting.Mode == RECORDER_MODE_REPLAY {
+			return nil, fmt.Errorf("%w: %s %s", ErrInteractionNotFound, recorded.Method, recorded.URL)
+		}
+	}
+
+	resp, err := rec.setting.Transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	interaction := &Interaction{Request: recorded, Response: RecordedResponse{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Header:     rec.redactHeader(resp.Header),
+	}}
+	interaction.Response.Body, interaction.Response.BodyBase64 = encodeRecordedBody(rec.redactBody(respBody))
+	if err = rec.append(interaction); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Save writes the cassette to CassettePath. Recorded interactions are saved
+// automatically, so it is only needed to rewrite a loaded cassette.
+func (rec *Recorder) Save() error {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return rec.save()
+}
+
+// match returns the first unused interaction matching the request. In
+// RECORDER_MODE_REPLAY, the last match is replayed again once all matches
+// were used, the other modes record a new interaction instead.
+func (rec *Recorder) match(recorded RecordedRequest) *Interaction {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	var reused *Interaction
+	for i, interaction := range rec.interactions {
+		candidate := interaction.Request
+		if candidate.Method != recorded.Method || candidate.URL != recorded.URL ||
+			candidate.Body != recorded.Body || candidate.BodyBase64 != recorded.BodyBase64 {
+			continue
+		}
+		if !rec.used[i] {
+			rec.used[i] = true
+			return interaction
+		}
+		if rec.setting.Mode == RECORDER_MODE_REPLAY {
+			reused = interaction
+		}
+	}
+	return reused
+}
+
+// append adds a recorded interaction and saves the cassette.
+func (rec *Recorder) append(interaction *Interaction) error {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.interactions = append(rec.interactions, interaction)
+	rec.used = append(rec.used, true)
+	return rec.save()
+}
+
+// save writes the cassette file. rec.mu must be held.
+func (rec *Recorder) save() error {
+	var data []byte
+	var err error
+	content := cassette{Interactions: rec.interactions}
+	if rec.isYAML() {
+		data, err = yaml.Marshal(content)
+	} else {
+		data, err = json.MarshalIndent(content, "", "  ")
+	}
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(rec.setting.CassettePath), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(rec.setting.CassettePath, data, 0o644)
+}
+
+// recordRequest builds the redacted form of a request used for recording and matching.
+func (rec *Recorder) recordRequest(req *http.Request, body []byte) RecordedRequest {
+	recordedURL := *req.URL
+	if len(rec.setting.RedactQueryParams) > 0 {
+		query := recordedURL.Query()
+		for _, name := range rec.setting.RedactQueryParams {
+			if query.Has(name) {
+				query.Set(name, LOG_REDACTED)
+			}
+		}
+		recordedURL.RawQuery = query.Encode()
+	}
+	recorded := RecordedRequest{
+		Method: req.Method,
+		URL:    recordedURL.String(),
+		Header: rec.redactHeader(req.Header),
+	}
+	recorded.Body, recorded.BodyBase64 = encodeRecordedBody(rec.redactBody(body))
+	return recorded
+}
+
+// redactHeader returns a copy of header with the configured headers redacted.
+func (rec *Recorder) redactHeader(header http.Header) http.Header {
+	redacted := header.Clone()
+	for _, name := range rec.setting.RedactHeaders {
+		if _, ok := redacted[http.CanonicalHeaderKey(name)]; ok {
+			redacted.Set(name, LOG_REDACTED)
+		}
+	}
+	return redacted
+}
+
+// redactBody normalizes a JSON body and redacts the configured paths.
+func (rec *Recorder) redactBody(body []byte) []byte {
+	var document interface{}
+	if len(body) == 0 || json.Unmarshal(body, &document) != nil {
+		return body
+	}
+	for _, path := range rec.redactPaths {
+		redactJSONPath(document, path)
+	}
+	normalized, err := json.Marshal(document)
+	if err != nil {
+		return body
+	}
+	return normalized
+}
+
+// isYAML reports whether the cassette is stored as YAML.
+func (rec *Recorder) isYAML() bool {
+	extension := strings.ToLower(filepath.Ext(rec.setting.CassettePath))
+	return extension == ".yaml" || extension == ".yml"
+}
+
+// toResponse rebuilds the recorded response for req.
+func (r RecordedResponse) toResponse(req *http.Request) (*http.Response, error) {
+	body := []byte(r.Body)
+	if r.BodyBase64 {
+		var err error
+		if body, err = base64.StdEncoding.DecodeString(r.Body); err != nil {
+			return nil, err
+		}
+	}
+	header := r.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        r.Status,
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// readRequestBody reads the request body and restores it for the transport.
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// encodeRecordedBody returns the body as text, base64 encoded when it is not valid UTF-8.
+func encodeRecordedBody(body []byte) (string, bool) {
+	if utf8.Valid(body) {
+		return string(body), false
+	}
+	return base64.StdEncoding.EncodeToString(body), true
+}
@@ -0,0 +1,151 @@
+package utilsx
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRecorderRecordAndReplay(t *testing.T) {
+	for _, name := range []string{"cassette.yaml", "cassette.json"} {
+		t.Run(name, func(t *testing.T) {
+			var calls int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&calls, 1)
+				body, _ := io.ReadAll(r.Body)
+				w.Header().Set("Content-Type", CONTENT_TYPE_JSON)
+				w.Write([]byte(`{"echo":` + string(body) + `,"token":"server-secret"}`))
+			}))
+			defer server.Close()
+			path := filepath.Join(t.TempDir(), name)
+
+			// 录制模式：请求真实服务并写入磁带，敏感信息被脱敏
+			recorder, err := NewRecorder(RecorderSetting{
+				Mode:              RECORDER_MODE_RECORD,
+				CassettePath:      path,
+				RedactQueryParams: []string{"key"},
+				RedactJSONPaths:   []string{"password", "echo.password", "token"},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			client := NewHttpClient(WithTransport(recorder))
+			resp, err := client.NewRequest(server.URL).SetUri("login").SetQueryParam("key", "key-1").
+				SetHeader("Authorization", "Bearer live").
+				SetJSONBody(map[string]string{"user": "bob", "password": "pass-1"}).Do(HTTP_METHOD_POST)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result, _ := resp.Result(); !strings.Contains(string(result), "server-secret") {
+				t.Fatalf("recording should return the real response, got %s", result)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, secret := range []string{"Bearer live", "key-1", "pass-1", "server-secret"} {
+				if strings.Contains(string(data), secret) {
+					t.Fatalf("cassette leaks %q:\n%s", secret, data)
+				}
+			}
+
+			// 回放模式：服务关闭后仍能返回录制的响应，密钥不同也能匹配
+			server.Close()
+			recorder, err = NewRecorder(RecorderSetting{
+				Mode:              RECORDER_MODE_REPLAY,
+				CassettePath:      path,
+				RedactQueryParams: []string{"key"},
+				RedactJSONPaths:   []string{"password", "echo.password", "token"},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			client = NewHttpClient(WithTransport(recorder))
+			resp, err = client.NewRequest(server.URL).SetUri("login").SetQueryParam("key", "key-2").
+				SetJSONBody(map[string]string{"password": "pass-2", "user": "bob"}).Do(HTTP_METHOD_POST)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result, _ := resp.Result(); resp.StatusCode() != http.StatusOK || !strings.Contains(string(result), `"user":"bob"`) {
+				t.Fatalf("unexpected replayed response %d %s", resp.StatusCode(), result)
+			}
+			if atomic.LoadInt32(&calls) != 1 {
+				t.Fatalf("replay should not reach the server, got %d calls", calls)
+			}
+		})
+	}
+}
+
+func TestRecorderReplayUnmatched(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	err := os.WriteFile(path, []byte(`{"interactions":[{"request":{"method":"GET","url":"http://example.test/a"},"response":{"status_code":200,"status":"200 OK","body":"a"}}]}`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder, err := NewRecorder(RecorderSetting{Mode: RECORDER_MODE_REPLAY, CassettePath: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewHttpClient(WithTransport(recorder))
+
+	// 方法、地址或请求体不匹配时直接报错
+	for _, method := range []HttpMethod{HTTP_METHOD_POST, HTTP_METHOD_GET} {
+		uri := "b"
+		if method == HTTP_METHOD_POST {
+			uri = "a"
+		}
+		_, err = client.NewRequest("http://example.test").SetUri(uri).Do(method)
+		if !errors.Is(err, ErrInteractionNotFound) {
+			t.Fatalf("%s %s: expected ErrInteractionNotFound, got %v", method, uri, err)
+		}
+	}
+
+	// 同一交互可被重复回放
+	for i := 0; i < 2; i++ {
+		resp, err := client.NewRequest("http://example.test").SetUri("a").Do(HTTP_METHOD_GET)
+		if err != nil {
+			t.Fatalf("replay %d: %v", i, err)
+		}
+		if result, _ := resp.Result(); string(result) != "a" {
+			t.Fatalf("replay %d: unexpected response %s", i, result)
+		}
+	}
+}
+
+func TestRecorderReplayOrRecord(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		w.Write([]byte{byte('0' + n), 0xff})
+	}))
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "cassette.yml")
+
+	// 缺失的交互被录制，已有的交互按顺序回放
+	for round := 0; round < 2; round++ {
+		recorder, err := NewRecorder(RecorderSetting{Mode: RECORDER_MODE_REPLAY_OR_RECORD, CassettePath: path})
+		if err != nil {
+			t.Fatal(err)
+		}
+		client := NewHttpClient(WithTransport(recorder))
+		for _, want := range []byte{'1', '2'} {
+			resp, err := client.NewRequest(server.URL).Do(HTTP_METHOD_GET)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result, _ := resp.Result(); len(result) != 2 || result[0] != want || result[1] != 0xff {
+				t.Fatalf("round %d: expected %q, got %q", round, want, result)
+			}
+		}
+	}
+	if atomic.LoadInt32(&calls) != 2 {
+		t.Fatalf("expected 2 recorded calls, got %d", calls)
+	}
+}
@@ -0,0 +1,197 @@
+package utilsx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	RETRY_DEFAULT_MAX_ATTEMPTS int           = 3
+	RETRY_DEFAULT_BASE_DELAY   time.Duration = 100 * time.Millisecond
+	RETRY_DEFAULT_MAX_DELAY    time.Duration = 5 * time.Second
+)
+
+// RetryPolicy decides whether a failed attempt of an ApiRequest is retried.
+//
+// Retry is called after every attempt with the 1-based attempt number, the
+// sent request and either the received response or the transport error. It
+// returns how long to wait before the next attempt and whether to retry at all.
+type RetryPolicy interface {
+	Retry(attempt int, req *http.Request, resp *http.Response, err error) (time.Duration, bool)
+}
+
+// BackoffRetryPolicy retries with exponential backoff and full jitter.
+type BackoffRetryPolicy struct {
+	MaxAttempts        int           // total attempts including the first one
+	BaseDelay          time.Duration // backoff base delay
+	MaxDelay           time.Duration // backoff and Retry-After upper bound
+	RetryStatusCodes   []int         // response status codes worth retrying
+	RetryNetworkErrors bool          // retry transport errors such as connection resets
+	RetryNonIdempotent bool          // also retry POST and PATCH requests without idempotency key
+}
+
+// RetryError reports the number of attempts made before an ApiRequest gave up.
+type RetryError struct {
+	Attempts int
+	Err      error
+}
+
+// NewRetryPolicy creates a BackoffRetryPolicy with sane defaults.
+//
+// It retries network errors and 429, 502, 503 and 504 responses of idempotent
+// methods, up to maxAttempts attempts in total.
+//
+// Parameters:
+//   - maxAttempts: the maximum number of attempts, RETRY_DEFAULT_MAX_ATTEMPTS when <= 0.
+//
+// Returns:
+//   - *BackoffRetryPolicy: the retry policy.
+func NewRetryPolicy(maxAttempts int) *BackoffRetryPolicy {
+	if maxAttempts <= 0 {
+		maxAttempts = RETRY_DEFAULT_MAX_ATTEMPTS
+	}
+	return &BackoffRetryPolicy{
+		MaxAttempts: maxAttempts,
+		BaseDelay:   RETRY_DEFAULT_BASE_DELAY,
+		MaxDelay:    RETRY_DEFAULT_MAX_DELAY,
+		RetryStatusCodes: []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+		RetryNetworkErrors: true,
+	}
+}
+
+// Retry implements RetryPolicy.
+//
+// A Retry-After response header takes precedence over the computed backoff,
+// capped at MaxDelay when it is set.
+func (p *BackoffRetryPolicy) Retry(attempt int, req *http.Request, resp *http.Response, err error) (time.Duration, bool) {
+	if attempt >= p.MaxAttempts {
+		return 0, false
+	}
+	if !p.RetryNonIdempotent && !isIdempotentMethod(req.Method) && IdempotencyKeyFromContext(req.Context()) == "" {
+		return 0, false
+	}
+	if err != nil {
+		if !p.RetryNetworkErrors || !isRetryableError(err) {
+			return 0, false
+		}
+		return p.backoff(attempt), true
+	}
+	if !p.retryStatus(resp.StatusCode) {
+		return 0, false
+	}
+	if delay, ok := retryAfter(resp); ok {
+		if p.MaxDelay > 0 && delay > p.MaxDelay {
+			delay = p.MaxDelay
+		}
+		return delay, true
+	}
+	return p.backoff(attempt), true
+}
+
+// retryStatus reports whether the status code is listed in RetryStatusCodes.
+func (p *BackoffRetryPolicy) retryStatus(statusCode int) bool {
+	for _, code := range p.RetryStatusCodes {
+		if code == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns a random delay in [0, min(MaxDelay, BaseDelay*2^(attempt-1))).
+func (p *BackoffRetryPolicy) backoff(attempt int) time.Duration {
+	ceiling := p.BaseDelay << (attempt - 1)
+	if ceiling <= 0 || (p.MaxDelay > 0 && ceiling > p.MaxDelay) {
+		ceiling = p.MaxDelay
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling)))
+}
+
+// Error implements the error interface.
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("utilsx: request failed after %d attempts: %s", e.Attempts, e.Err.Error())
+}
+
+// Unwrap returns the error of the last attempt.
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// isIdempotentMethod reports whether method is idempotent as defined by RFC 9110.
+func isIdempotentMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace,
+		http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// isRetryableError reports whether a transport error is worth retrying.
+//
+// Errors caused by the caller ending the context are never retried.
+func isRetryableError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// retryAfter parses the Retry-After header in either delay-seconds or HTTP-date form.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		delay := time.Until(date)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
+
+// sleepContext waits for delay or until ctx is done, whichever happens first.
+func sleepContext(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// drainBody discards a bounded amount of the body so the connection can be reused.
+func drainBody(body io.ReadCloser) {
+	io.Copy(io.Discard, io.LimitReader(body, 4096))
+	body.Close()
+}
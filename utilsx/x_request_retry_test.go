@@ -0,0 +1,84 @@
+package utilsx
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryStatus(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != `{"name":"golix"}` {
+			t.Errorf("unexpected body: %s", body)
+		}
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	resp, err := NewHttpRequest(server.URL).
+		SetBody("name", "golix").
+		SetRetryPolicy(NewRetryPolicy(3)).
+		Do(HTTP_METHOD_PUT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Attempts() != 3 || !resp.Success() {
+		t.Fatalf("expected success after 3 attempts, got %d", resp.Attempts())
+	}
+}
+
+func TestRetryNonIdempotent(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	// POST请求默认不重试
+	NewHttpRequest(server.URL).SetRetryPolicy(NewRetryPolicy(3)).Do(HTTP_METHOD_POST)
+	if calls != 1 {
+		t.Fatalf("expected a single attempt, got %d", calls)
+	}
+}
+
+func TestRetryNetworkError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	address := server.URL
+	server.Close()
+
+	policy := NewRetryPolicy(2)
+	policy.BaseDelay = time.Millisecond
+	_, err := NewHttpRequest(address).SetRetryPolicy(policy).Do(HTTP_METHOD_GET)
+	var retryErr *RetryError
+	if !errors.As(err, &retryErr) || retryErr.Attempts != 2 {
+		t.Fatalf("expected RetryError after 2 attempts, got %v", err)
+	}
+}
+
+func TestRetryAfterMaxDelay(t *testing.T) {
+	policy := NewRetryPolicy(3)
+	policy.MaxDelay = 50 * time.Millisecond
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	resp := &http.Response{StatusCode: http.StatusServiceUnavailable, Header: http.Header{"Retry-After": {"86400"}}}
+
+	// Retry-After超过MaxDelay时按MaxDelay等待
+	if delay, retry := policy.Retry(1, req, resp, nil); !retry || delay != policy.MaxDelay {
+		t.Fatalf("expected retry after %s, got %s %v", policy.MaxDelay, delay, retry)
+	}
+	resp.Header.Set("Retry-After", "0")
+	if delay, retry := policy.Retry(1, req, resp, nil); !retry || delay != 0 {
+		t.Fatalf("expected immediate retry, got %s %v", delay, retry)
+	}
+}
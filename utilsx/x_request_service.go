@@ -0,0 +1,149 @@
+package utilsx
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ServiceSetting configures a ServiceClient.
+type ServiceSetting struct {
+	BaseURL     string        // base URL of the service, such as "https://api.example.com/v1", https when without scheme
+	Headers     http.Header   // headers of every request
+	Query       url.Values    // query parameters of every request
+	UserAgent   string        // User-Agent header of every request
+	Timeout     time.Duration // timeout of every request, the client timeout when 0
+	Auth        AuthProvider  // authentication of every request
+	Middlewares []Middleware  // middlewares of every request, run after the client middlewares
+	Client      *HttpClient   // client sending the requests, DefaultHttpClient() when nil
+}
+
+// ServiceClient creates requests relative to the base URL of a service, with
+// the defaults of its ServiceSetting. It is safe for concurrent use.
+//
+// A partner SDK only wraps it with its endpoints:
+//
+//	type RegionApi struct{ *ServiceClient }
+//
+//	func (a RegionApi) Get(ctx context.Context, id string) (Region, error) {
+//		return DoJSONContext[Region](ctx, a.NewRequest("regions/"+url.PathEscape(id)), HTTP_METHOD_GET)
+//	}
+type ServiceClient struct {
+	base    *url.URL
+	setting ServiceSetting
+}
+
+// NewServiceClient creates a new ServiceClient.
+//
+// Parameters:
+//   - setting: the service setting, its headers, query and middlewares are copied.
+//
+// Returns:
+//   - *ServiceClient: the created service client.
+//   - error: the error of an invalid base URL.
+func NewServiceClient(setting ServiceSetting) (*ServiceClient, error) {
+	address := setting.BaseURL
+	if !strings.Contains(address, "://") {
+		address = "https://" + address
+	}
+	base, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+	if base.Host == "" {
+		return nil, errors.New("utilsx: service base URL has no host")
+	}
+	if len(base.Query()) > 0 {
+		setting.Query = mergeValues(base.Query(), setting.Query)
+	}
+	base.RawQuery, base.Fragment = "", ""
+
+	setting.Headers = setting.Headers.Clone()
+	setting.Query = mergeValues(nil, setting.Query)
+	setting.Middlewares = append([]Middleware(nil), setting.Middlewares...)
+	return &ServiceClient{base: base, setting: setting}, nil
+}
+
+// NewRequest creates a request relative to the base URL.
+//
+// The path is an escaped URL reference: segments built from user input must
+// be escaped with url.PathEscape, so that they may contain "/", "?" or "%".
+// It is joined to the base path with a single slash, keeping its trailing
+// slash, and may carry query parameters, such as "users?active=1", which
+// override the default ones. An invalid path is returned when the request is sent.
+//
+// Parameters:
+//   - path: the escaped path of the endpoint, relative to the base URL.
+//
+// Returns:
+//   - ExecutableApiRequest: the created request.
+func (s *ServiceClient) NewRequest(path string) ExecutableApiRequest {
+	client := s.setting.Client
+	if client == nil {
+		client = DefaultHttpClient()
+	}
+	address := *s.base
+	ref, err := url.Parse(path)
+	if err == nil {
+		joinUrlPath(&address, ref)
+	}
+	r := newApiRequest(client, address.String())
+	if err != nil {
+		r.pathErr = fmt.Errorf("utilsx: invalid service request path: %w", err)
+	}
+
+	for key, values := range s.setting.Query {
+		r.query[key] = append([]string(nil), values...)
+	}
+	if err == nil {
+		for key, values := range ref.Query() {
+			r.query[key] = values
+		}
+	}
+
+	for key, values := range s.setting.Headers {
+		r.headers[key] = append([]string(nil), values...)
+	}
+	if s.setting.UserAgent != "" {
+		r.headers.Set("User-Agent", s.setting.UserAgent)
+	}
+	if s.setting.Timeout > 0 {
+		r.timeout = s.setting.Timeout
+	}
+	r.auth = s.setting.Auth
+	r.middlewares = append(r.middlewares, s.setting.Middlewares...)
+	return r
+}
+
+// BaseURL returns the base URL of the service.
+func (s *ServiceClient) BaseURL() string {
+	return s.base.String()
+}
+
+// joinUrlPath appends the path of ref to the path of u with a single slash.
+//
+// Unlike path.Join, it keeps the trailing slash of ref and leaves dot
+// segments and escaped characters of both paths untouched.
+func joinUrlPath(u *url.URL, ref *url.URL) {
+	escaped := strings.TrimLeft(ref.EscapedPath(), "/")
+	if escaped == "" {
+		return
+	}
+	u.RawPath = strings.TrimSuffix(u.EscapedPath(), "/") + "/" + escaped
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + strings.TrimLeft(ref.Path, "/")
+}
+
+// mergeValues returns a copy of values with the keys of override replaced.
+func mergeValues(values, override url.Values) url.Values {
+	merged := make(url.Values, len(values)+len(override))
+	for key, list := range values {
+		merged[key] = append([]string(nil), list...)
+	}
+	for key, list := range override {
+		merged[key] = append([]string(nil), list...)
+	}
+	return merged
+}
@@ -0,0 +1,88 @@
+package utilsx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestServiceClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.RequestURI() + "|" + r.Header.Get("User-Agent") + "|" + r.Header.Get("X-Tenant") + "|" + r.Header.Get("Authorization")))
+	}))
+	defer server.Close()
+
+	var middlewareCalls int
+	service, err := NewServiceClient(ServiceSetting{
+		BaseURL:   server.URL + "/v1/?lang=en",
+		Headers:   http.Header{"X-Tenant": {"acme"}},
+		Query:     url.Values{"region": {"eu"}},
+		UserAgent: "acme-sdk/1.0",
+		Timeout:   time.Second,
+		Auth:      BearerAuth("token"),
+		Middlewares: []Middleware{func(next RoundTripFunc) RoundTripFunc {
+			return func(req *http.Request) (*http.Response, error) {
+				middlewareCalls++
+				return next(req)
+			}
+		}},
+		Client: NewHttpClient(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 路径相对基础地址拼接，保留末尾斜杠，请求参数覆盖默认参数，已转义的路径不再重复转义
+	cases := map[string]string{
+		"users":   "/v1/users?lang=en&region=eu",
+		"/users/": "/v1/users/?lang=en&region=eu",
+		"users/" + url.PathEscape("a b/c?%") + "?region=us": "/v1/users/a%20b%2Fc%3F%25?lang=en&region=us",
+	}
+	for path, expected := range cases {
+		resp, err := service.NewRequest(path).Do(HTTP_METHOD_GET)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result, _ := resp.Result(); string(result) != expected+"|acme-sdk/1.0|acme|Bearer token" {
+			t.Fatalf("%s: unexpected request %s", path, result)
+		}
+	}
+	if middlewareCalls != len(cases) {
+		t.Fatalf("expected %d middleware calls, got %d", len(cases), middlewareCalls)
+	}
+
+	// 请求上的修改不影响服务的默认值
+	service.NewRequest("users").SetHeader("X-Tenant", "other").AddQueryParam("region", "us")
+	resp, err := service.NewRequest("users").Do(HTTP_METHOD_GET)
+	if result, _ := resp.Result(); err != nil || string(result) != "/v1/users?lang=en&region=eu|acme-sdk/1.0|acme|Bearer token" {
+		t.Fatalf("defaults were modified: %s %v", result, err)
+	}
+
+	// 路径错误不会被之后成功的SetQueryStruct清除
+	if _, err = service.NewRequest("users/%zz").SetQueryStruct(testQueryPaging{}).Do(HTTP_METHOD_GET); err == nil {
+		t.Fatal("expected invalid path error")
+	}
+	if _, err = NewServiceClient(ServiceSetting{BaseURL: "https:///v1"}); err == nil {
+		t.Fatal("expected missing host error")
+	}
+	if service, _ = NewServiceClient(ServiceSetting{BaseURL: "api.example.com/v1"}); service.BaseURL() != "https://api.example.com/v1" {
+		t.Fatalf("expected https base URL, got %s", service.BaseURL())
+	}
+}
+
+func TestGetUrlPathJoin(t *testing.T) {
+	// 拼接路径时保留末尾斜杠和基础路径中的转义字符
+	cases := []struct{ address, uri, expected string }{
+		{"https://api.example.com", "regions", "https://api.example.com/regions"},
+		{"https://api.example.com/v1/", "/regions/", "https://api.example.com/v1/regions/"},
+		{"https://api.example.com/files/a%2Fb", "c", "https://api.example.com/files/a%2Fb/c"},
+		{"https://api.example.com/v1", "", "https://api.example.com/v1"},
+	}
+	for _, c := range cases {
+		if got := NewHttpClient().NewRequest(c.address).SetUri(c.uri).(*ApiRequest).GetUrl(); got != c.expected {
+			t.Fatalf("expected %s, got %s", c.expected, got)
+		}
+	}
+}
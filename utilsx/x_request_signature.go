@@ -0,0 +1,256 @@
+package utilsx
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	SIGNATURE_DEFAULT_HEADER           string        = "X-Signature"
+	SIGNATURE_DEFAULT_TIMESTAMP_HEADER string        = "X-Timestamp"
+	SIGNATURE_DEFAULT_NONCE_HEADER     string        = "X-Nonce"
+	SIGNATURE_DEFAULT_KEY_ID_HEADER    string        = "X-Key-Id"
+	SIGNATURE_DEFAULT_TOLERANCE        time.Duration = 5 * time.Minute
+	SIGNATURE_MAX_BODY_SIZE            int64         = 10 * 1024 * 1024
+)
+
+var (
+	ErrSignatureMissing  = errors.New("utilsx: signature headers missing")
+	ErrSignatureInvalid  = errors.New("utilsx: signature invalid")
+	ErrSignatureExpired  = errors.New("utilsx: signature timestamp outside tolerance")
+	ErrSignatureReplayed = errors.New("utilsx: signature nonce already used")
+)
+
+// NonceStore remembers the nonces of verified requests to reject replays.
+//
+// Remember returns true when nonce was not seen in the last ttl. redisx.NonceStore
+// implements it for verifiers running on several replicas.
+type NonceStore interface {
+	Remember(ctx context.Context, nonce string, ttl time.Duration) (bool, error)
+}
+
+// HmacSetting configures an HmacSigner and the matching HmacVerifier.
+//
+// The signature is the hex encoded HMAC-SHA256 of the canonical string made of
+// the method, the escaped path, the sorted query, the hex encoded SHA-256 of
+// the body, the timestamp and the nonce, separated by newlines.
+type HmacSetting struct {
+	Secret []byte
+	KeyID  string // optional key identifier sent along the signature
+
+	SignatureHeader string // SIGNATURE_DEFAULT_HEADER when empty
+	TimestampHeader string // SIGNATURE_DEFAULT_TIMESTAMP_HEADER when empty
+	NonceHeader     string // SIGNATURE_DEFAULT_NONCE_HEADER when empty
+	KeyIDHeader     string // SIGNATURE_DEFAULT_KEY_ID_HEADER when empty
+
+	Tolerance  time.Duration // accepted clock skew of the verifier, SIGNATURE_DEFAULT_TOLERANCE when 0
+	NonceStore NonceStore    // replay protection of the verifier, in memory when nil
+}
+
+// HmacSigner signs outgoing requests. It implements AuthProvider, so it can be
+// attached with SetAuth or WithAuth.
+type HmacSigner struct {
+	setting HmacSetting
+}
+
+// HmacVerifier verifies the signature of incoming requests, such as webhooks.
+type HmacVerifier struct {
+	setting HmacSetting
+	now     func() time.Time
+}
+
+type memoryNonceStore struct {
+	mu        sync.Mutex
+	nonces    map[string]time.Time
+	lastSweep time.Time
+}
+
+// NewHmacSigner creates a new HmacSigner.
+//
+// It takes the signature setting.
+// It returns a pointer to the created HmacSigner.
+func NewHmacSigner(setting HmacSetting) *HmacSigner {
+	return &HmacSigner{setting: setting.withDefaults()}
+}
+
+// NewHmacVerifier creates a new HmacVerifier.
+//
+// It takes the signature setting, which must match the one of the signer.
+// It returns a pointer to the created HmacVerifier.
+func NewHmacVerifier(setting HmacSetting) *HmacVerifier {
+	setting = setting.withDefaults()
+	if setting.NonceStore == nil {
+		setting.NonceStore = NewMemoryNonceStore()
+	}
+	return &HmacVerifier{setting: setting, now: time.Now}
+}
+
+// NewMemoryNonceStore creates an in-process NonceStore.
+func NewMemoryNonceStore() NonceStore {
+	return &memoryNonceStore{nonces: make(map[string]time.Time)}
+}
+
+// Apply implements AuthProvider by signing the request.
+func (s *HmacSigner) Apply(req *http.Request) error {
+	body, err := signatureBody(req)
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := randomHex(16)
+	req.Header.Set(s.setting.TimestampHeader, timestamp)
+	req.Header.Set(s.setting.NonceHeader, nonce)
+	if s.setting.KeyID != "" {
+		req.Header.Set(s.setting.KeyIDHeader, s.setting.KeyID)
+	}
+	req.Header.Set(s.setting.SignatureHeader, s.setting.sign(req, body, timestamp, nonce))
+	return nil
+}
+
+// Verify checks the signature, timestamp and nonce of req.
+//
+// The body is read and restored, so handlers can still read it.
+//
+// Parameters:
+//   - req: the incoming request.
+//
+// Returns:
+//   - error: nil when the request is authentic, otherwise one of the
+//     ErrSignature* errors or the nonce store error.
+func (v *HmacVerifier) Verify(req *http.Request) error {
+	signature := req.Header.Get(v.setting.SignatureHeader)
+	timestamp := req.Header.Get(v.setting.TimestampHeader)
+	nonce := req.Header.Get(v.setting.NonceHeader)
+	if signature == "" || timestamp == "" || nonce == "" {
+		return ErrSignatureMissing
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrSignatureInvalid
+	}
+	skew := v.now().Sub(time.Unix(seconds, 0))
+	if skew < -v.setting.Tolerance || skew > v.setting.Tolerance {
+		return ErrSignatureExpired
+	}
+
+	var body []byte
+	if req.Body != nil {
+		body, err = io.ReadAll(io.LimitReader(req.Body, SIGNATURE_MAX_BODY_SIZE+1))
+		req.Body.Close()
+		if err != nil {
+			return err
+		}
+		if int64(len(body)) > SIGNATURE_MAX_BODY_SIZE {
+			return fmt.Errorf("%w: body too large", ErrSignatureInvalid)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	expected := v.setting.sign(req, body, timestamp, nonce)
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return ErrSignatureInvalid
+	}
+
+	// a nonce only has to be remembered while its timestamp is accepted
+	fresh, err := v.setting.NonceStore.Remember(req.Context(), nonce, 2*v.setting.Tolerance)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrSignatureReplayed
+	}
+	return nil
+}
+
+// Middleware rejects requests failing Verify with 401 Unauthorized.
+func (v *HmacVerifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if err := v.Verify(req); err != nil {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// Remember implements NonceStore.
+func (s *memoryNonceStore) Remember(_ context.Context, nonce string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if now.Sub(s.lastSweep) > ttl {
+		for key, expiry := range s.nonces {
+			if now.After(expiry) {
+				delete(s.nonces, key)
+			}
+		}
+		s.lastSweep = now
+	}
+	if expiry, ok := s.nonces[nonce]; ok && now.Before(expiry) {
+		return false, nil
+	}
+	s.nonces[nonce] = now.Add(ttl)
+	return true, nil
+}
+
+// withDefaults fills the empty fields of the setting with the default values.
+func (s HmacSetting) withDefaults() HmacSetting {
+	if s.SignatureHeader == "" {
+		s.SignatureHeader = SIGNATURE_DEFAULT_HEADER
+	}
+	if s.TimestampHeader == "" {
+		s.TimestampHeader = SIGNATURE_DEFAULT_TIMESTAMP_HEADER
+	}
+	if s.NonceHeader == "" {
+		s.NonceHeader = SIGNATURE_DEFAULT_NONCE_HEADER
+	}
+	if s.KeyIDHeader == "" {
+		s.KeyIDHeader = SIGNATURE_DEFAULT_KEY_ID_HEADER
+	}
+	if s.Tolerance <= 0 {
+		s.Tolerance = SIGNATURE_DEFAULT_TOLERANCE
+	}
+	return s
+}
+
+// sign computes the signature of a request.
+func (s HmacSetting) sign(req *http.Request, body []byte, timestamp, nonce string) string {
+	bodyHash := sha256.Sum256(body)
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.Query().Encode(),
+		hex.EncodeToString(bodyHash[:]),
+		timestamp,
+		nonce,
+	}, "\n")
+	mac := hmac.New(sha256.New, s.Secret)
+	mac.Write([]byte(canonical))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// signatureBody returns the body of an outgoing request without consuming it.
+func signatureBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody == nil {
+		return nil, errors.New("utilsx: cannot sign a streamed request body")
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
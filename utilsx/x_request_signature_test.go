@@ -0,0 +1,66 @@
+package utilsx
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHmacSignature(t *testing.T) {
+	setting := HmacSetting{Secret: []byte("secret"), KeyID: "partner"}
+	verifier := NewHmacVerifier(setting)
+
+	var lastRequest *http.Request
+	var lastBody string
+	server := httptest.NewServer(verifier.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		lastRequest, lastBody = r.Clone(r.Context()), string(body)
+		w.Write([]byte("verified"))
+	})))
+	defer server.Close()
+
+	_, err := NewHttpRequest(server.URL).
+		SetUri("payments").
+		SetQueryParam("b", "2").
+		SetQueryParam("a", "1").
+		SetBody("amount", 100).
+		SetAuth(NewHmacSigner(setting)).
+		Do(HTTP_METHOD_POST)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastBody != `{"amount":100}` || lastRequest.Header.Get("X-Key-Id") != "partner" {
+		t.Fatalf("handler must read the verified body, got %q", lastBody)
+	}
+
+	// 重放同一个请求会被拒绝
+	replay := lastRequest.Clone(lastRequest.Context())
+	replay.Body = io.NopCloser(strings.NewReader(lastBody))
+	if err = verifier.Verify(replay); !errors.Is(err, ErrSignatureReplayed) {
+		t.Fatalf("expected replay error, got %v", err)
+	}
+
+	// 篡改请求体后签名失效
+	tampered := lastRequest.Clone(lastRequest.Context())
+	tampered.Body = io.NopCloser(strings.NewReader(`{"amount":1}`))
+	if err = NewHmacVerifier(setting).Verify(tampered); !errors.Is(err, ErrSignatureInvalid) {
+		t.Fatalf("expected invalid signature, got %v", err)
+	}
+
+	// 超出时间容差的请求会被拒绝
+	expired := NewHmacVerifier(setting)
+	expired.now = func() time.Time { return time.Now().Add(time.Hour) }
+	late := lastRequest.Clone(lastRequest.Context())
+	late.Body = io.NopCloser(strings.NewReader(lastBody))
+	if err = expired.Verify(late); !errors.Is(err, ErrSignatureExpired) {
+		t.Fatalf("expected expired signature, got %v", err)
+	}
+
+	if _, err = NewHttpRequest(server.URL).Do(HTTP_METHOD_GET); !IsUnauthorized(err) {
+		t.Fatalf("unsigned request must be rejected, got %v", err)
+	}
+}
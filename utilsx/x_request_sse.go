@@ -0,0 +1,330 @@
+package utilsx
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	CONTENT_TYPE_EVENT_STREAM    string        = "text/event-stream"
+	SSE_DEFAULT_RETRY_DELAY      time.Duration = 3 * time.Second
+	STREAM_DEFAULT_MAX_LINE_SIZE int           = 1024 * 1024
+)
+
+// ErrNotEventStream is returned when the response is not a text/event-stream.
+var ErrNotEventStream = errors.New("utilsx: response is not an event stream")
+
+// Event is a Server-Sent Event.
+type Event struct {
+	ID    string        // last event ID of the stream, from this event or a previous one
+	Event string        // event type, "message" when the event has none
+	Data  string        // event data, lines joined with "\n"
+	Retry time.Duration // retry field of the event, 0 when absent
+}
+
+// EventStreamSetting configures an EventStream.
+type EventStreamSetting struct {
+	Method        HttpMethod    // HTTP_METHOD_GET when empty
+	LastEventID   string        // ID of the last event already received, to resume a stream
+	RetryDelay    time.Duration // reconnection delay until the server sets one, SSE_DEFAULT_RETRY_DELAY when 0
+	MaxReconnects int           // consecutive reconnections without event before giving up, 0 means no limit, negative disables reconnection
+	MaxLineSize   int           // longest accepted line, STREAM_DEFAULT_MAX_LINE_SIZE when 0
+}
+
+// EventStream reads the Server-Sent Events of a request (text/event-stream).
+//
+// A dropped connection is reopened after the retry delay, with the
+// Last-Event-ID header of the last received event. A 204 No Content response
+// ends the stream. The request is reused for every connection, so it must not
+// be used concurrently, and its timeout is disabled: use ctx to bound the stream.
+//
+// Events are read one at a time as the caller asks for them, so a slow
+// consumer slows the reading of the connection down:
+//
+//	events := NewEventStream(req, EventStreamSetting{})
+//	defer events.Close()
+//	for events.Next(ctx) {
+//		event := events.Event()
+//	}
+//	if err := events.Err(); err != nil {
+//	}
+type EventStream struct {
+	req     ExecutableApiRequest
+	setting EventStreamSetting
+
+	body        io.ReadCloser
+	lines       *bufio.Scanner
+	event       Event
+	lastEventID string
+	retryDelay  time.Duration
+	reconnects  int
+	done        bool
+	err         error
+}
+
+// NewEventStream creates a new EventStream.
+//
+// Parameters:
+//   - req: the request of the stream.
+//   - setting: the event stream setting.
+//
+// Returns:
+//   - *EventStream: the created event stream, connected by the first Next call.
+func NewEventStream(req ExecutableApiRequest, setting EventStreamSetting) *EventStream {
+	if setting.Method == "" {
+		setting.Method = HTTP_METHOD_GET
+	}
+	if setting.RetryDelay <= 0 {
+		setting.RetryDelay = SSE_DEFAULT_RETRY_DELAY
+	}
+	if setting.MaxLineSize <= 0 {
+		setting.MaxLineSize = STREAM_DEFAULT_MAX_LINE_SIZE
+	}
+	req.SetHeader("Accept", CONTENT_TYPE_EVENT_STREAM).SetHeader("Cache-Control", "no-cache").SetTimeout(0)
+	return &EventStream{
+		req:         req,
+		setting:     setting,
+		lastEventID: setting.LastEventID,
+		retryDelay:  setting.RetryDelay,
+	}
+}
+
+// Next reads the next event, connecting or reconnecting when needed.
+//
+// It returns false at the end of the stream or on an error, which Err then returns.
+func (s *EventStream) Next(ctx context.Context) bool {
+	for !s.done && s.err == nil {
+		if s.lines == nil {
+			if err := s.connect(ctx); err != nil {
+				s.retry(ctx, err)
+			}
+			if s.lines == nil {
+				continue
+			}
+		}
+		if s.readEvent() {
+			s.reconnects = 0
+			return true
+		}
+		err := s.lines.Err()
+		s.disconnect()
+		if err == nil {
+			err = io.ErrUnexpectedEOF
+		}
+		s.retry(ctx, err)
+	}
+	return false
+}
+
+// Event returns the current event.
+func (s *EventStream) Event() Event {
+	return s.event
+}
+
+// LastEventID returns the ID sent in the Last-Event-ID header on reconnection.
+func (s *EventStream) LastEventID() string {
+	return s.lastEventID
+}
+
+// Err returns the error that stopped the stream.
+func (s *EventStream) Err() error {
+	return s.err
+}
+
+// Close closes the connection, Next then returns false.
+func (s *EventStream) Close() error {
+	s.done = true
+	s.disconnect()
+	return nil
+}
+
+// Chan returns a channel receiving the events, closed at the end of the
+// stream, after which Err returns the error that stopped it.
+//
+// The channel is unbuffered, so events are only read as fast as they are
+// received. The stream is closed when ctx is done.
+func (s *EventStream) Chan(ctx context.Context) <-chan Event {
+	return streamChan(ctx, s.Next, s.Event, s.Close, &s.err)
+}
+
+// connect opens a connection, resuming from the last event ID.
+func (s *EventStream) connect(ctx context.Context) error {
+	if s.lastEventID != "" {
+		s.req.SetHeader("Last-Event-ID", s.lastEventID)
+	}
+	stream, err := s.req.DoStream(ctx, s.setting.Method)
+	if err != nil {
+		return err
+	}
+	if stream.StatusCode == http.StatusNoContent {
+		stream.Body.Close()
+		s.done = true
+		return nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(stream.Header.Get("Content-Type"))
+	if stream.StatusCode != http.StatusOK || mediaType != CONTENT_TYPE_EVENT_STREAM {
+		stream.Body.Close()
+		s.err = ErrNotEventStream
+		return nil
+	}
+	s.body = stream.Body
+	s.lines = newLineScanner(stream.Body, s.setting.MaxLineSize)
+	return nil
+}
+
+// disconnect closes the current connection.
+func (s *EventStream) disconnect() {
+	if s.body != nil {
+		s.body.Close()
+	}
+	s.body, s.lines = nil, nil
+}
+
+// retry waits before the next connection after err, or stops the stream when
+// err cannot be retried or the reconnections are exhausted.
+func (s *EventStream) retry(ctx context.Context, err error) {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		s.err = contextError(ctx, ctxErr)
+		return
+	}
+	var httpErr *HttpError
+	if s.done || s.err != nil || (errors.As(err, &httpErr) && !IsRetryable(err)) {
+		if s.err == nil && !s.done {
+			s.err = err
+		}
+		return
+	}
+	if errors.Is(err, bufio.ErrTooLong) || s.setting.MaxReconnects < 0 ||
+		(s.setting.MaxReconnects > 0 && s.reconnects >= s.setting.MaxReconnects) {
+		s.err = err
+		return
+	}
+	s.reconnects++
+	if sleepErr := sleepContext(ctx, s.retryDelay); sleepErr != nil {
+		s.err = contextError(ctx, sleepErr)
+	}
+}
+
+// readEvent reads lines until an event is dispatched, and returns false at
+// the end of the connection.
+func (s *EventStream) readEvent() bool {
+	var (
+		data      strings.Builder
+		eventType string
+		retry     time.Duration
+		hasData   bool
+	)
+	for s.lines.Scan() {
+		line := s.lines.Text()
+		if line == "" {
+			if !hasData {
+				// an event without data is not dispatched
+				eventType, retry = "", 0
+				continue
+			}
+			if eventType == "" {
+				eventType = "message"
+			}
+			s.event = Event{ID: s.lastEventID, Event: eventType, Data: strings.TrimSuffix(data.String(), "\n"), Retry: retry}
+			return true
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			eventType = value
+		case "data":
+			data.WriteString(value)
+			data.WriteByte('\n')
+			hasData = true
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				s.lastEventID = value
+			}
+		case "retry":
+			if milliseconds, err := strconv.ParseUint(value, 10, 63); err == nil {
+				retry = time.Duration(milliseconds) * time.Millisecond
+				s.retryDelay = retry
+			}
+		}
+	}
+	return false
+}
+
+// newLineScanner returns a scanner of the lines of body ended by CRLF, LF or
+// CR, without the UTF-8 byte order mark of the first line.
+func newLineScanner(body io.Reader, maxLineSize int) *bufio.Scanner {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, min(4096, maxLineSize)), maxLineSize)
+	first := true
+	scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		advance, line, err := scanLines(data, atEOF)
+		if first && line != nil {
+			first = false
+			line = bytes.TrimPrefix(line, []byte("\xef\xbb\xbf"))
+		}
+		return advance, line, err
+	})
+	return scanner
+}
+
+// scanLines is a bufio.SplitFunc splitting on CRLF, LF and CR.
+func scanLines(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\n' {
+			return i + 1, data[:i], nil
+		}
+		if i+1 < len(data) {
+			if data[i+1] == '\n' {
+				return i + 2, data[:i], nil
+			}
+			return i + 1, data[:i], nil
+		}
+		if atEOF {
+			return i + 1, data[:i], nil
+		}
+		// wait for the next byte to tell CR from CRLF
+		return 0, nil, nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// streamChan sends the items of a stream to a channel from a goroutine.
+//
+// The stream is closed when ctx is done, and err set to its error.
+func streamChan[T any](ctx context.Context, next func(context.Context) bool, item func() T, closeStream func() error, err *error) <-chan T {
+	items := make(chan T)
+	go func() {
+		// the stream is closed before the channel, so that Err is set when the receiver sees it closed
+		defer close(items)
+		defer closeStream()
+		for next(ctx) {
+			select {
+			case items <- item():
+			case <-ctx.Done():
+				if *err == nil {
+					*err = contextError(ctx, ctx.Err())
+				}
+				return
+			}
+		}
+	}()
+	return items
+}
@@ -0,0 +1,128 @@
+package utilsx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEventStream(t *testing.T) {
+	var (
+		mu           sync.Mutex
+		connections  int
+		lastEventIDs []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		connections++
+		count := connections
+		lastEventIDs = append(lastEventIDs, r.Header.Get("Last-Event-ID"))
+		mu.Unlock()
+		switch count {
+		case 1:
+			w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+			// BOM、注释、CRLF/CR换行、多行data、无data的事件
+			fmt.Fprint(w, "\xef\xbb\xbf: welcome\r\n")
+			fmt.Fprint(w, "retry: 10\r\nid: 1\r\ndata: first\r\ndata:  line\r\n\r\n")
+			fmt.Fprint(w, "event: update\rid: 2\rdata:second\r\r")
+			fmt.Fprint(w, "id: 3\nevent: ignored\n\n")
+			fmt.Fprint(w, "data: unfinished")
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 3:
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: resumed\n\n")
+		default:
+			// 204结束事件流
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	events := NewEventStream(NewHttpClient().NewRequest(server.URL), EventStreamSetting{RetryDelay: time.Hour})
+	defer events.Close()
+	var received []Event
+	for events.Next(context.Background()) {
+		received = append(received, events.Event())
+	}
+	if err := events.Err(); err != nil {
+		t.Fatal(err)
+	}
+	expected := []Event{
+		{ID: "1", Event: "message", Data: "first\n line", Retry: 10 * time.Millisecond},
+		{ID: "2", Event: "update", Data: "second"},
+		{ID: "3", Event: "message", Data: "resumed"},
+	}
+	if fmt.Sprint(received) != fmt.Sprint(expected) {
+		t.Fatalf("unexpected events %v", received)
+	}
+	// 重连时携带最后的事件ID
+	if strings.Join(lastEventIDs, ",") != ",3,3,3" {
+		t.Fatalf("unexpected Last-Event-ID headers %q", lastEventIDs)
+	}
+}
+
+func TestEventStreamErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/json":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte("{}"))
+		case "/missing":
+			http.NotFound(w, r)
+		case "/down":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: once\n\n")
+		}
+	}))
+	defer server.Close()
+	client := NewHttpClient()
+
+	// 非事件流响应和不可重试的状态码直接返回错误
+	events := NewEventStream(client.NewRequest(server.URL).SetUri("json"), EventStreamSetting{})
+	if events.Next(context.Background()) || !errors.Is(events.Err(), ErrNotEventStream) {
+		t.Fatalf("expected ErrNotEventStream, got %v", events.Err())
+	}
+	events = NewEventStream(client.NewRequest(server.URL).SetUri("missing"), EventStreamSetting{})
+	var httpErr *HttpError
+	if events.Next(context.Background()) || !errors.As(events.Err(), &httpErr) || httpErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 error, got %v", events.Err())
+	}
+
+	// 连续重连次数用尽后返回最后的错误，禁用重连时连接断开即返回
+	events = NewEventStream(client.NewRequest(server.URL).SetUri("down"), EventStreamSetting{RetryDelay: time.Millisecond, MaxReconnects: 2})
+	if events.Next(context.Background()) || !errors.As(events.Err(), &httpErr) || httpErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 error, got %v", events.Err())
+	}
+	events = NewEventStream(client.NewRequest(server.URL), EventStreamSetting{MaxReconnects: -1})
+	count := 0
+	for events.Next(context.Background()) {
+		count++
+	}
+	if count != 1 || !errors.Is(events.Err(), io.ErrUnexpectedEOF) {
+		t.Fatalf("expected stream to stop without reconnection, got %d events and %v", count, events.Err())
+	}
+
+	// 使用channel接收事件，取消ctx后关闭channel
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events = NewEventStream(client.NewRequest(server.URL), EventStreamSetting{RetryDelay: time.Millisecond})
+	received := 0
+	for range events.Chan(ctx) {
+		if received++; received == 3 {
+			cancel()
+		}
+	}
+	if received < 3 || !errors.Is(events.Err(), context.Canceled) {
+		t.Fatalf("expected canceled stream, got %d events and %v", received, events.Err())
+	}
+}
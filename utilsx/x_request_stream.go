@@ -0,0 +1,271 @@
+package utilsx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// ErrResponseTooLarge is returned when the response body exceeds the size set by SetMaxResponseSize.
+var ErrResponseTooLarge = errors.New("utilsx: response body too large")
+
+// ProgressFunc is called while a response body is read with the number of
+// bytes transferred so far and the total size, -1 when the size is unknown.
+type ProgressFunc func(transferred, total int64)
+
+// StreamResponse is a response whose body is handed to the caller unread.
+//
+// The caller must close Body, which also releases the request timeout.
+type StreamResponse struct {
+	StatusCode    int           // response status code
+	Header        http.Header   // response headers
+	ContentLength int64         // response body length, -1 when unknown
+	Body          io.ReadCloser // response body, limited and reporting progress
+}
+
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+type progressBody struct {
+	io.ReadCloser
+	transferred int64
+	total       int64
+	progress    ProgressFunc
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// SetMaxResponseSize limits the size of the response body read by Do, DoStream and the downloads.
+//
+// Parameters:
+//   - size: the maximum body size in bytes, 0 means no limit.
+//
+// Returns:
+//   - executableApiRequest: The modified apiRequest struct.
+func (r *ApiRequest) SetMaxResponseSize(size int64) ExecutableApiRequest {
+	r.maxResponseSize = size
+	return r
+}
+
+// SetProgress sets a callback reporting the progress of reading the response body.
+//
+// Parameters:
+//   - progress: the progress callback.
+//
+// Returns:
+//   - executableApiRequest: The modified apiRequest struct.
+func (r *ApiRequest) SetProgress(progress ProgressFunc) ExecutableApiRequest {
+	r.progress = progress
+	return r
+}
+
+// DoStream sends the request and returns the response with its body unread.
+//
+// The timeout set by SetTimeout keeps running while the body is read, use
+// SetTimeout(0) and ctx to control long transfers.
+//
+// Parameters:
+//   - ctx: the context of the request.
+//   - method: the HTTP method of the request.
+//
+// Returns:
+//   - *StreamResponse: the response, its body must be closed by the caller.
+//   - error: the request error, or an *HttpError when the response status
+//     code is 400 or above, in which case the body is already closed.
+func (r *ApiRequest) DoStream(ctx context.Context, method HttpMethod) (*StreamResponse, error) {
+	return r.stream(ctx, method, nil, 0)
+}
+
+// Download sends the request and copies the response body to writer.
+//
+// Parameters:
+//   - ctx: the context of the request.
+//   - method: the HTTP method of the request.
+//   - writer: the destination of the response body.
+//
+// Returns:
+//   - int64: the number of bytes written.
+//   - error: the request, status or copy error.
+func (r *ApiRequest) Download(ctx context.Context, method HttpMethod, writer io.Writer) (int64, error) {
+	stream, err := r.stream(ctx, method, nil, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer stream.Body.Close()
+	return io.Copy(writer, stream.Body)
+}
+
+// DownloadFile sends the request and saves the response body to filePath.
+//
+// The body is written to filePath + ".part" first and renamed once complete.
+// When a partial file is left by an interrupted download, the transfer is
+// resumed with a Range request if the server supports it, and restarted
+// from scratch otherwise.
+//
+// Parameters:
+//   - ctx: the context of the request.
+//   - method: the HTTP method of the request.
+//   - filePath: the destination file.
+//
+// Returns:
+//   - int64: the size of the downloaded file.
+//   - error: the request, status or file error.
+func (r *ApiRequest) DownloadFile(ctx context.Context, method HttpMethod, filePath string) (int64, error) {
+	partPath := filePath + ".part"
+	var offset int64
+	if info, err := os.Stat(partPath); err == nil {
+		offset = info.Size()
+	}
+	// the size limit applies to the whole file, not to each resumed transfer
+	if r.maxResponseSize > 0 && offset > r.maxResponseSize {
+		return offset, ErrResponseTooLarge
+	}
+	var header http.Header
+	if offset > 0 {
+		header = http.Header{"Range": {fmt.Sprintf("bytes=%d-", offset)}}
+	}
+
+	stream, err := r.stream(ctx, method, header, offset)
+	if err != nil {
+		// the partial file already holds the whole content
+		if offset > 0 && IsStatus(err, http.StatusRequestedRangeNotSatisfiable) {
+			return offset, os.Rename(partPath, filePath)
+		}
+		return 0, err
+	}
+	defer stream.Body.Close()
+
+	flag := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if stream.StatusCode == http.StatusPartialContent {
+		if start, ok := contentRangeStart(stream.Header.Get("Content-Range")); !ok || start != offset {
+			return 0, fmt.Errorf("utilsx: unexpected content range %q for offset %d", stream.Header.Get("Content-Range"), offset)
+		}
+		flag = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	} else {
+		offset = 0
+	}
+	file, err := os.OpenFile(partPath, flag, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	written, err := io.Copy(file, stream.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return offset + written, err
+	}
+	return offset + written, os.Rename(partPath, filePath)
+}
+
+// stream sends the request and wraps the unread response body.
+//
+// It takes the context of the call, the method of the request, extra headers
+// and the number of bytes already transferred by a previous call, which is
+// added to the reported progress.
+// It returns the streamed response and an error.
+func (r *ApiRequest) stream(ctx context.Context, method HttpMethod, header http.Header, offset int64) (*StreamResponse, error) {
+	ctx, cancel := r.withTimeout(ctx)
+
+	resp, err := r.send(ctx, method, header)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer cancel()
+		defer resp.Body.Close()
+		r.apiResponseData, _ = io.ReadAll(io.LimitReader(resp.Body, int64(HTTP_ERROR_MAX_BODY_SIZE)))
+		r.apiResponseError = r.newHttpError()
+		return nil, r.apiResponseError
+	}
+
+	// a server ignoring the Range header sends the whole content again
+	if resp.StatusCode != http.StatusPartialContent {
+		offset = 0
+	}
+	return &StreamResponse{
+		StatusCode:    resp.StatusCode,
+		Header:        resp.Header,
+		ContentLength: resp.ContentLength,
+		Body:          &cancelBody{ReadCloser: r.limitBody(resp, offset), cancel: cancel},
+	}, nil
+}
+
+// limitBody wraps the response body with the size limit and the progress callback.
+//
+// It takes the response and the number of bytes transferred by a previous
+// call, which count against the size limit.
+// It returns the wrapped body.
+func (r *ApiRequest) limitBody(resp *http.Response, offset int64) io.ReadCloser {
+	body := resp.Body
+	if r.maxResponseSize > 0 {
+		body = &limitedBody{ReadCloser: body, remaining: r.maxResponseSize - offset}
+	}
+	if r.progress != nil {
+		total := resp.ContentLength
+		if total >= 0 {
+			total += offset
+		}
+		body = &progressBody{ReadCloser: body, transferred: offset, total: total, progress: r.progress}
+	}
+	return body
+}
+
+// Read implements io.Reader, failing with ErrResponseTooLarge once the limit is exceeded.
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		// probe for one more byte to tell an exact fit from an oversized body
+		var probe [1]byte
+		n, err := b.ReadCloser.Read(probe[:])
+		if n > 0 {
+			return 0, ErrResponseTooLarge
+		}
+		return 0, err
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
+
+// Read implements io.Reader, reporting the progress after every read.
+func (b *progressBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.transferred += int64(n)
+		b.progress(b.transferred, b.total)
+	}
+	return n, err
+}
+
+// Close closes the body and releases the request context.
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// contentRangeStart parses the first byte position of a Content-Range header
+// such as "bytes 100-999/1000".
+func contentRangeStart(contentRange string) (int64, bool) {
+	var start, end int64
+	if !strings.HasPrefix(contentRange, "bytes ") {
+		return 0, false
+	}
+	if _, err := fmt.Sscanf(contentRange, "bytes %d-%d", &start, &end); err != nil {
+		return 0, false
+	}
+	return start, true
+}
@@ -0,0 +1,46 @@
+//go:build go1.23
+
+package utilsx
+
+import (
+	"context"
+	"iter"
+)
+
+// Events returns an iterator over the events, for use with range.
+//
+// The iteration stops after yielding a non-nil error, and the stream is
+// closed when the loop ends:
+//
+//	for event, err := range events.Events(ctx) {
+//		if err != nil {
+//			return err
+//		}
+//	}
+func (s *EventStream) Events(ctx context.Context) iter.Seq2[Event, error] {
+	return streamSeq(ctx, s.Next, s.Event, s.Close, s.Err)
+}
+
+// Items returns an iterator over the decoded values, for use with range.
+//
+// The iteration stops after yielding a non-nil error, and the stream is
+// closed when the loop ends.
+func (s *JSONStream[T]) Items(ctx context.Context) iter.Seq2[T, error] {
+	return streamSeq(ctx, s.Next, s.Item, s.Close, s.Err)
+}
+
+// streamSeq returns an iterator over the items of a stream.
+func streamSeq[T any](ctx context.Context, next func(context.Context) bool, item func() T, closeStream func() error, err func() error) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		defer closeStream()
+		for next(ctx) {
+			if !yield(item(), nil) {
+				return
+			}
+		}
+		if err := err(); err != nil {
+			var zero T
+			yield(zero, err)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+//go:build go1.23
+
+package utilsx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStreamIterators(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < 10; i++ {
+			fmt.Fprintf(w, "id: %d\ndata: %d\n\n", i, i)
+		}
+	}))
+	defer server.Close()
+
+	// 使用range遍历事件，提前break时关闭事件流
+	events := NewEventStream(NewHttpClient().NewRequest(server.URL), EventStreamSetting{})
+	var data []string
+	for event, err := range events.Events(context.Background()) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data = append(data, event.Data); len(data) == 3 {
+			break
+		}
+	}
+	if fmt.Sprint(data) != "[0 1 2]" || events.Next(context.Background()) {
+		t.Fatalf("unexpected events %v", data)
+	}
+
+	ndjson := newTestNDJSONServer()
+	defer ndjson.Close()
+	logs := NewJSONStream[testLogEntry](NewHttpClient().NewRequest(ndjson.URL).SetUri("invalid"), JSONStreamSetting{})
+	count, failed := 0, false
+	for _, err := range logs.Items(context.Background()) {
+		if err != nil {
+			failed = true
+			continue
+		}
+		count++
+	}
+	if count != 1 || !failed {
+		t.Fatalf("expected one item and an error, got %d, %v", count, failed)
+	}
+}
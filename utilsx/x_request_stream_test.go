@@ -0,0 +1,138 @@
+package utilsx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testStreamContent = strings.Repeat("golix", 1000)
+
+func newTestStreamServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		// ServeContent支持Range请求
+		http.ServeContent(w, r, "content.txt", time.Time{}, strings.NewReader(testStreamContent))
+	}))
+}
+
+func TestDoStream(t *testing.T) {
+	server := newTestStreamServer()
+	defer server.Close()
+
+	var transferred, total int64
+	stream, err := NewHttpRequest(server.URL).
+		SetProgress(func(n, size int64) { transferred, total = n, size }).
+		DoStream(context.Background(), HTTP_METHOD_GET)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := io.ReadAll(stream.Body)
+	stream.Body.Close()
+	if err != nil || string(content) != testStreamContent {
+		t.Fatalf("unexpected stream content: %v", err)
+	}
+	if transferred != int64(len(testStreamContent)) || total != transferred {
+		t.Fatalf("unexpected progress %d/%d", transferred, total)
+	}
+
+	if _, err = NewHttpRequest(server.URL).SetUri("missing").DoStream(context.Background(), HTTP_METHOD_GET); !IsNotFound(err) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
+
+func TestMaxResponseSize(t *testing.T) {
+	server := newTestStreamServer()
+	defer server.Close()
+
+	var buffer bytes.Buffer
+	_, err := NewHttpRequest(server.URL).SetMaxResponseSize(100).Download(context.Background(), HTTP_METHOD_GET, &buffer)
+	if !errors.Is(err, ErrResponseTooLarge) {
+		t.Fatalf("expected ErrResponseTooLarge, got %v", err)
+	}
+	_, err = NewHttpRequest(server.URL).SetMaxResponseSize(int64(len(testStreamContent))).Do(HTTP_METHOD_GET)
+	if err != nil {
+		t.Fatalf("body of exactly the limit must be accepted, got %v", err)
+	}
+}
+
+func TestDownloadFileResume(t *testing.T) {
+	server := newTestStreamServer()
+	defer server.Close()
+
+	// 模拟中断的下载，从已下载的位置继续
+	filePath := filepath.Join(t.TempDir(), "content.txt")
+	if err := os.WriteFile(filePath+".part", []byte(testStreamContent[:1234]), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var firstProgress int64 = -1
+	size, err := NewHttpRequest(server.URL).
+		SetProgress(func(n, total int64) {
+			if firstProgress < 0 {
+				firstProgress = n
+			}
+		}).
+		DownloadFile(context.Background(), HTTP_METHOD_GET, filePath)
+	if err != nil || size != int64(len(testStreamContent)) {
+		t.Fatalf("unexpected download result %d %v", size, err)
+	}
+	content, _ := os.ReadFile(filePath)
+	if string(content) != testStreamContent {
+		t.Fatal("resumed file content mismatch")
+	}
+	if firstProgress <= 1234 {
+		t.Fatalf("download was not resumed, first progress %d", firstProgress)
+	}
+	if _, err = os.Stat(filePath + ".part"); !os.IsNotExist(err) {
+		t.Fatal("partial file must be renamed")
+	}
+}
+
+func TestDownloadFileResumeMaxResponseSize(t *testing.T) {
+	server := newTestStreamServer()
+	defer server.Close()
+
+	// 续传时已下载的部分也计入大小限制
+	filePath := filepath.Join(t.TempDir(), "content.txt")
+	if err := os.WriteFile(filePath+".part", []byte(testStreamContent[:3000]), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	limit := int64(len(testStreamContent) - 1)
+	if _, err := NewHttpRequest(server.URL).SetMaxResponseSize(limit).
+		DownloadFile(context.Background(), HTTP_METHOD_GET, filePath); !errors.Is(err, ErrResponseTooLarge) {
+		t.Fatalf("expected ErrResponseTooLarge, got %v", err)
+	}
+	if _, err := os.Stat(filePath); !os.IsNotExist(err) {
+		t.Fatal("oversized file must not be completed")
+	}
+
+	// 已下载的部分超过限制时不再发送请求
+	if err := os.WriteFile(filePath+".part", []byte(testStreamContent), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewHttpRequest(server.URL).SetMaxResponseSize(100).
+		DownloadFile(context.Background(), HTTP_METHOD_GET, filePath); !errors.Is(err, ErrResponseTooLarge) {
+		t.Fatalf("expected ErrResponseTooLarge, got %v", err)
+	}
+
+	// 限制等于文件大小时可以完成续传
+	if err := os.WriteFile(filePath+".part", []byte(testStreamContent[:3000]), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	size, err := NewHttpRequest(server.URL).SetMaxResponseSize(int64(len(testStreamContent))).
+		DownloadFile(context.Background(), HTTP_METHOD_GET, filePath)
+	if err != nil || size != int64(len(testStreamContent)) {
+		t.Fatalf("unexpected download result %d %v", size, err)
+	}
+}
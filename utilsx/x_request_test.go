@@ -1,11 +1,55 @@
 package utilsx
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
 
+// hostTransport sends the requests to the host of target, keeping their original URL.
+type hostTransport struct {
+	target *url.URL
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t hostTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	clone := req.Clone(req.Context())
+	clone.URL.Scheme, clone.URL.Host, clone.Host = t.target.Scheme, t.target.Host, ""
+	return http.DefaultTransport.RoundTrip(clone)
+}
+
 func TestGetRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write([]byte(`{"code":200,"msg":"success"}`))
+	}))
+	target, _ := url.Parse(server.URL)
+	cassette := filepath.Join(t.TempDir(), "x_request_get.yaml")
+
+	// 先录制请求，本地服务代替真实服务
+	recorder, err := NewRecorder(RecorderSetting{Mode: RECORDER_MODE_RECORD, CassettePath: cassette, Transport: hostTransport{target: target}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = NewHttpClient(WithTransport(recorder)).NewRequest("https://qqlykm.cn/api/free/history/get").Do(HTTP_METHOD_GET); err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+
+	// 回放录制好的请求，测试不依赖网络
+	recorder, err = NewRecorder(RecorderSetting{Mode: RECORDER_MODE_REPLAY, CassettePath: cassette})
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := SetDefaultHttpClient(NewHttpClient(WithTransport(recorder)))
+	defer SetDefaultHttpClient(previous)
+
 	// 第一种写法，可直接传入完整url
 	req := NewHttpRequest("https://qqlykm.cn/api/free/history/get")
 	req.SetTimeout(5 * time.Second)
@@ -18,5 +62,71 @@ func TestGetRequest(t *testing.T) {
 	// req := NewHttpRequest("qqlykm.cn")
 	// req.SetUri("api/free/history/get")
 
-	req.SetTimeout(1 * time.Second).Do(HTTP_METHOD_GET)
+	resp, err := req.SetTimeout(1 * time.Second).Do(HTTP_METHOD_GET)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result, _ := resp.Result(); resp.StatusCode() != http.StatusOK || string(result) != `{"code":200,"msg":"success"}` {
+		t.Fatalf("unexpected response %d %s", resp.StatusCode(), result)
+	}
+}
+
+func TestDoContextDeadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	// 调用方的截止时间短于SetTimeout时，以调用方的截止时间为准
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := NewHttpRequest(server.URL).SetTimeout(5*time.Second).DoContext(ctx, HTTP_METHOD_GET)
+	if !errors.Is(err, ErrRequestTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline error, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatalf("request did not respect caller deadline")
+	}
+}
+
+func TestDoContextCanceled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	_, err := NewHttpRequest(server.URL).DoContext(ctx, HTTP_METHOD_GET)
+	if !errors.Is(err, ErrRequestCanceled) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected canceled error, got %v", err)
+	}
+}
+
+func TestMultiValuedHeadersAndQuery(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.RawQuery + "|" + strings.Join(r.Header.Values("Accept"), ";") + "|" + r.Header.Get("X-Trace")))
+	}))
+	defer server.Close()
+
+	// 同名参数和请求头可以发送多个值，Del删除全部值
+	resp, err := NewHttpClient().NewRequest(server.URL+"?ids=0").
+		AddQueryParam("ids", "1").AddQueryParam("ids", "2").
+		SetQueryParam("page", "1").AddQueryParam("page", "2").DelQueryParam("page").
+		AddHeader("Accept", "application/json").AddHeader("accept", "text/plain").
+		SetHeader("X-Trace", "a").DelHeader("x-trace").
+		Do(HTTP_METHOD_GET)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result, _ := resp.Result(); string(result) != "ids=0&ids=1&ids=2|application/json;text/plain|" {
+		t.Fatalf("unexpected request %s", result)
+	}
 }
@@ -0,0 +1,231 @@
+package utilsx
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	TLS_DEFAULT_MIN_VERSION     uint16        = tls.VersionTLS12
+	TLS_DEFAULT_RELOAD_INTERVAL time.Duration = 30 * time.Second
+)
+
+// TLSSetting configures the TLS connections of an HttpClient.
+//
+// The client certificate is reloaded from disk when its files change, so that
+// rotated certificates are used by new connections without a restart.
+// Connections kept alive in the pool keep the certificate they were opened with.
+type TLSSetting struct {
+	CAFile     string // PEM bundle of the trusted CAs, the system pool when empty
+	CAPEM      []byte // PEM encoded trusted CAs, added to CAFile
+	CertFile   string // PEM client certificate for mTLS
+	KeyFile    string // PEM private key of the client certificate
+	ServerName string // overrides the name used to verify the server certificate
+	MinVersion uint16 // TLS_DEFAULT_MIN_VERSION when 0
+
+	// ReloadInterval is the minimum time between two checks of the client
+	// certificate files, TLS_DEFAULT_RELOAD_INTERVAL when 0. A negative value
+	// disables the reload.
+	ReloadInterval time.Duration
+
+	// InsecureSkipVerify disables the server certificate verification. It is
+	// meant for development environments only and logged as an error.
+	InsecureSkipVerify bool
+}
+
+type certReloader struct {
+	certFile string
+	keyFile  string
+	interval time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+	checked time.Time
+}
+
+// NewTLSConfig builds a tls.Config from the setting, to be used with WithTLSConfig.
+//
+// Parameters:
+//   - setting: the TLS setting.
+//
+// Returns:
+//   - *tls.Config: the TLS configuration.
+//   - error: the error loading the CA bundle or the client certificate.
+func NewTLSConfig(setting TLSSetting) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion:         setting.MinVersion,
+		ServerName:         setting.ServerName,
+		InsecureSkipVerify: setting.InsecureSkipVerify,
+	}
+	if config.MinVersion == 0 {
+		config.MinVersion = TLS_DEFAULT_MIN_VERSION
+	}
+
+	if setting.CAFile != "" || len(setting.CAPEM) > 0 {
+		pool := x509.NewCertPool()
+		if setting.CAFile != "" {
+			data, err := os.ReadFile(setting.CAFile)
+			if err != nil {
+				return nil, err
+			}
+			if !pool.AppendCertsFromPEM(data) {
+				return nil, fmt.Errorf("utilsx: no certificate found in %s", setting.CAFile)
+			}
+		}
+		if len(setting.CAPEM) > 0 && !pool.AppendCertsFromPEM(setting.CAPEM) {
+			return nil, errors.New("utilsx: no certificate found in CAPEM")
+		}
+		config.RootCAs = pool
+	}
+
+	if setting.CertFile != "" || setting.KeyFile != "" {
+		if setting.CertFile == "" || setting.KeyFile == "" {
+			return nil, errors.New("utilsx: CertFile and KeyFile must be set together")
+		}
+		interval := setting.ReloadInterval
+		if interval == 0 {
+			interval = TLS_DEFAULT_RELOAD_INTERVAL
+		}
+		reloader := &certReloader{certFile: setting.CertFile, keyFile: setting.KeyFile, interval: interval, now: time.Now}
+		if _, err := reloader.load(); err != nil {
+			return nil, err
+		}
+		config.GetClientCertificate = reloader.GetClientCertificate
+	}
+	return config, nil
+}
+
+// WithTLSConfig sets the TLS configuration of the client transport.
+//
+// The configuration is cloned, later changes to it have no effect. Settings of
+// earlier WithInsecureSkipVerify and WithMinTLSVersion options are kept on top
+// of it, the higher minimum version winning, whatever the option order.
+func WithTLSConfig(config *tls.Config) HttpClientOption {
+	return func(c *HttpClient) {
+		cloned := config.Clone()
+		if previous := c.transport.TLSClientConfig; previous != nil {
+			cloned.InsecureSkipVerify = cloned.InsecureSkipVerify || previous.InsecureSkipVerify
+			cloned.MinVersion = max(cloned.MinVersion, previous.MinVersion)
+		}
+		c.transport.TLSClientConfig = cloned
+		if config.InsecureSkipVerify {
+			logInsecureSkipVerify()
+		}
+	}
+}
+
+// WithMinTLSVersion sets the minimum TLS version accepted by the client, such as tls.VersionTLS13.
+func WithMinTLSVersion(version uint16) HttpClientOption {
+	return func(c *HttpClient) {
+		c.tlsConfig().MinVersion = version
+	}
+}
+
+// WithInsecureSkipVerify disables the verification of the server certificates.
+//
+// It is meant for development environments only, every client created with it
+// is logged as an error.
+func WithInsecureSkipVerify() HttpClientOption {
+	return func(c *HttpClient) {
+		c.tlsConfig().InsecureSkipVerify = true
+		logInsecureSkipVerify()
+	}
+}
+
+// WithProxy sends the requests through an explicit proxy instead of the one
+// from the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+//
+// The http, https and socks5 schemes are supported, credentials are taken
+// from the URL user info. A nil URL disables proxying.
+func WithProxy(proxy *url.URL) HttpClientOption {
+	return func(c *HttpClient) {
+		if proxy == nil {
+			c.transport.Proxy = nil
+			return
+		}
+		c.transport.Proxy = http.ProxyURL(proxy)
+	}
+}
+
+// tlsConfig returns the TLS configuration of the transport, creating it when needed.
+func (c *HttpClient) tlsConfig() *tls.Config {
+	if c.transport.TLSClientConfig == nil {
+		c.transport.TLSClientConfig = &tls.Config{MinVersion: TLS_DEFAULT_MIN_VERSION}
+	}
+	return c.transport.TLSClientConfig
+}
+
+// GetClientCertificate implements tls.Config.GetClientCertificate.
+//
+// A certificate that fails to reload is logged and the previous one is kept.
+func (l *certReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.interval < 0 || l.now().Sub(l.checked) < l.interval {
+		return l.cert, nil
+	}
+	if _, err := l.reload(); err != nil {
+		slog.Default().Log(context.Background(), slog.LevelError, "client certificate reload failed",
+			slog.String("cert_file", l.certFile), slog.String("error", err.Error()))
+	}
+	return l.cert, nil
+}
+
+// load reads the certificate files.
+//
+// It returns whether a new certificate was loaded and an error.
+func (l *certReloader) load() (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.reload()
+}
+
+// reload reads the certificate files when they were modified. l.mu must be held.
+func (l *certReloader) reload() (bool, error) {
+	l.checked = l.now()
+	modTime, err := latestModTime(l.certFile, l.keyFile)
+	if err != nil {
+		return false, err
+	}
+	if l.cert != nil && modTime.Equal(l.modTime) {
+		return false, nil
+	}
+	cert, err := tls.LoadX509KeyPair(l.certFile, l.keyFile)
+	if err != nil {
+		return false, err
+	}
+	l.cert, l.modTime = &cert, modTime
+	return true, nil
+}
+
+// latestModTime returns the most recent modification time of the files.
+func latestModTime(files ...string) (time.Time, error) {
+	var latest time.Time
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// logInsecureSkipVerify warns that a client does not verify server certificates.
+func logInsecureSkipVerify() {
+	slog.Default().Log(context.Background(), slog.LevelError,
+		"TLS certificate verification is DISABLED for this http client, never use InsecureSkipVerify in production")
+}
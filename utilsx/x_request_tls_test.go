@@ -0,0 +1,224 @@
+package utilsx
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// newTestCert 生成测试证书，parent为nil时生成自签名CA
+func newTestCert(t *testing.T, name string, parent *testCert) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	parentCert, parentKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		parentCert, parentKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parentCert, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func (c *testCert) tlsCertificate(t *testing.T) tls.Certificate {
+	cert, err := tls.X509KeyPair(c.certPEM, c.keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func (c *testCert) write(t *testing.T, dir string, modTime time.Time) (string, string) {
+	certFile, keyFile := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	for file, data := range map[string][]byte{certFile: c.certPEM, keyFile: c.keyPEM} {
+		if err := os.WriteFile(file, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(file, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return certFile, keyFile
+}
+
+func TestTLSMutualAuthAndReload(t *testing.T) {
+	ca := newTestCert(t, "test-ca", nil)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{newTestCert(t, "server", ca).tlsCertificate(t)},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(caFile, ca.certPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := newTestCert(t, "client-1", ca).write(t, dir, time.Now().Add(-time.Minute))
+
+	config, err := NewTLSConfig(TLSSetting{
+		CAFile:         caFile,
+		CertFile:       certFile,
+		KeyFile:        keyFile,
+		MinVersion:     tls.VersionTLS12,
+		ReloadInterval: time.Nanosecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewHttpClient(WithTLSConfig(config))
+	defer client.CloseIdleConnections()
+
+	// 使用私有CA校验服务端证书，并携带客户端证书
+	resp, err := client.NewRequest(server.URL).Do(HTTP_METHOD_GET)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result, _ := resp.Result(); string(result) != "client-1" {
+		t.Fatalf("unexpected client certificate %s", result)
+	}
+
+	// 证书文件更新后，新连接使用新证书
+	newTestCert(t, "client-2", ca).write(t, dir, time.Now())
+	client.CloseIdleConnections()
+	resp, err = client.NewRequest(server.URL).Do(HTTP_METHOD_GET)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result, _ := resp.Result(); string(result) != "client-2" {
+		t.Fatalf("certificate was not reloaded, got %s", result)
+	}
+
+	// 未配置私有CA时无法校验服务端证书
+	if _, err = NewHttpClient().NewRequest(server.URL).Do(HTTP_METHOD_GET); err == nil {
+		t.Fatal("expected certificate verification error")
+	}
+}
+
+func TestTLSSettingErrors(t *testing.T) {
+	if _, err := NewTLSConfig(TLSSetting{CAPEM: []byte("not a certificate")}); err == nil {
+		t.Fatal("expected invalid CA error")
+	}
+	if _, err := NewTLSConfig(TLSSetting{CertFile: "client.crt"}); err == nil {
+		t.Fatal("expected missing key error")
+	}
+	if _, err := NewTLSConfig(TLSSetting{CertFile: "missing.crt", KeyFile: "missing.key"}); err == nil {
+		t.Fatal("expected missing file error")
+	}
+}
+
+func TestInsecureSkipVerifyIsLogged(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	var logs bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	defer slog.SetDefault(previous)
+
+	// 跳过证书校验必须输出错误日志
+	client := NewHttpClient(WithInsecureSkipVerify(), WithMinTLSVersion(tls.VersionTLS13))
+	defer client.CloseIdleConnections()
+	if !strings.Contains(logs.String(), "level=ERROR") || !strings.Contains(logs.String(), "DISABLED") {
+		t.Fatalf("insecure skip verify was not logged: %s", logs.String())
+	}
+	if _, err := client.NewRequest(server.URL).Do(HTTP_METHOD_GET); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTLSOptionOrder(t *testing.T) {
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	defer slog.SetDefault(previous)
+
+	// WithTLSConfig不会丢弃之前选项的设置，选项顺序不影响结果
+	orders := [][]HttpClientOption{
+		{WithInsecureSkipVerify(), WithMinTLSVersion(tls.VersionTLS13), WithTLSConfig(&tls.Config{ServerName: "api.internal"})},
+		{WithTLSConfig(&tls.Config{ServerName: "api.internal"}), WithInsecureSkipVerify(), WithMinTLSVersion(tls.VersionTLS13)},
+	}
+	for i, opts := range orders {
+		config := NewHttpClient(opts...).transport.TLSClientConfig
+		if !config.InsecureSkipVerify || config.MinVersion != tls.VersionTLS13 || config.ServerName != "api.internal" {
+			t.Fatalf("order %d: unexpected TLS config %+v", i, config)
+		}
+	}
+}
+
+func TestWithProxy(t *testing.T) {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 代理收到的是完整的目标地址
+		w.Write([]byte("proxied " + r.URL.Host + " " + r.Header.Get("Proxy-Authorization")))
+	}))
+	defer proxy.Close()
+
+	proxyURL, _ := url.Parse(proxy.URL)
+	proxyURL.User = url.UserPassword("user", "secret")
+	client := NewHttpClient(WithProxy(proxyURL))
+	resp, err := client.NewRequest("http://service.internal").SetUri("ping").Do(HTTP_METHOD_GET)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result, _ := resp.Result(); !strings.HasPrefix(string(result), "proxied service.internal Basic ") {
+		t.Fatalf("request did not go through the proxy: %s", result)
+	}
+}
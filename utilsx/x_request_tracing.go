@@ -0,0 +1,257 @@
+package utilsx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	TRACE_HEADER_PARENT string = "traceparent"
+	TRACE_HEADER_STATE  string = "tracestate"
+	TRACE_FLAG_SAMPLED  byte   = 0x01
+)
+
+var ErrInvalidTraceparent = errors.New("utilsx: invalid traceparent")
+
+// SpanContext identifies a span of a distributed trace (W3C Trace Context).
+type SpanContext struct {
+	TraceID    [16]byte
+	SpanID     [8]byte
+	Flags      byte   // trace flags, TRACE_FLAG_SAMPLED when the trace is recorded
+	TraceState string // vendor specific tracestate header value
+}
+
+// Tracer starts the client span of every attempt sent through TracingMiddleware.
+//
+// The returned context carries the new span and the returned span gives the
+// SpanContext injected in the request headers.
+type Tracer interface {
+	Start(ctx context.Context, req *http.Request) (context.Context, Span)
+}
+
+// Span is a client span started by a Tracer.
+type Span interface {
+	SpanContext() SpanContext
+	// End ends the span with the response status code, 0 when the attempt
+	// failed, and the transport error.
+	End(statusCode int, err error)
+}
+
+// ClientSpan is a client span ended by W3CTracer.
+type ClientSpan struct {
+	SpanContext SpanContext
+	Parent      SpanContext // zero for the root span of a new trace
+	Method      string
+	Host        string
+	StatusCode  int // 0 when the attempt failed
+	Err         error
+	Start       time.Time
+	Duration    time.Duration
+}
+
+// W3CTracer is the Tracer used by TracingMiddleware when none is given.
+//
+// Its spans are children of the SpanContext set by ContextWithSpanContext, or
+// the roots of new sampled traces. OnEnd, when set, receives every ended span.
+type W3CTracer struct {
+	OnEnd func(ctx context.Context, span ClientSpan)
+}
+
+type w3cSpan struct {
+	ctx  context.Context
+	data ClientSpan
+	end  func(ctx context.Context, span ClientSpan)
+}
+
+type spanContextKey struct{}
+
+// tracedBody ends the span of a response when its body is closed.
+type tracedBody struct {
+	io.ReadCloser
+	once  sync.Once
+	onEnd func()
+}
+
+// TracingMiddleware starts a client span for every attempt of a request and
+// injects its traceparent and tracestate headers.
+//
+// The span ends when the response body is closed, or when the attempt fails.
+// Retries get a span each, all children of the span of the request context.
+//
+// Parameters:
+//   - tracer: the tracer, a W3CTracer that only propagates the trace when nil.
+//
+// Returns:
+//   - Middleware: the tracing middleware.
+func TracingMiddleware(tracer Tracer) Middleware {
+	if tracer == nil {
+		tracer = W3CTracer{}
+	}
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			ctx, span := tracer.Start(req.Context(), req)
+			req = req.WithContext(ctx)
+			InjectSpanContext(req.Header, span.SpanContext())
+
+			resp, err := next(req)
+			if err != nil || resp.Body == nil {
+				statusCode := 0
+				if resp != nil {
+					statusCode = resp.StatusCode
+				}
+				span.End(statusCode, err)
+				return resp, err
+			}
+			resp.Body = &tracedBody{
+				ReadCloser: resp.Body,
+				onEnd: func() {
+					span.End(resp.StatusCode, nil)
+				},
+			}
+			return resp, nil
+		}
+	}
+}
+
+// ContextWithSpanContext returns a copy of ctx carrying the span, the parent
+// of the client spans of W3CTracer.
+func ContextWithSpanContext(ctx context.Context, span SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, span)
+}
+
+// SpanContextFromContext returns the span carried by ctx, the zero SpanContext when none.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	span, _ := ctx.Value(spanContextKey{}).(SpanContext)
+	return span
+}
+
+// ExtractSpanContext reads the traceparent and tracestate headers of an
+// incoming request, to continue its trace in the outgoing calls.
+//
+// Parameters:
+//   - header: the request headers.
+//
+// Returns:
+//   - SpanContext: the span of the caller.
+//   - error: ErrInvalidTraceparent when the header is missing or malformed.
+func ExtractSpanContext(header http.Header) (SpanContext, error) {
+	span, err := ParseTraceparent(header.Get(TRACE_HEADER_PARENT))
+	if err != nil {
+		return SpanContext{}, err
+	}
+	span.TraceState = strings.Join(header.Values(TRACE_HEADER_STATE), ",")
+	return span, nil
+}
+
+// InjectSpanContext sets the traceparent and tracestate headers of span,
+// and leaves header untouched when span is not valid.
+func InjectSpanContext(header http.Header, span SpanContext) {
+	if !span.IsValid() {
+		return
+	}
+	header.Set(TRACE_HEADER_PARENT, span.Traceparent())
+	if span.TraceState != "" {
+		header.Set(TRACE_HEADER_STATE, span.TraceState)
+	} else {
+		header.Del(TRACE_HEADER_STATE)
+	}
+}
+
+// ParseTraceparent parses a traceparent header value.
+//
+// Parameters:
+//   - value: the header value, such as "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01".
+//
+// Returns:
+//   - SpanContext: the parsed span, without trace state.
+//   - error: ErrInvalidTraceparent when the value is malformed.
+func ParseTraceparent(value string) (SpanContext, error) {
+	var span SpanContext
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	// future versions may append fields, version 00 has exactly four
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return span, ErrInvalidTraceparent
+	}
+	var version, flags [1]byte
+	if !decodeTraceHex(version[:], parts[0]) || !decodeTraceHex(span.TraceID[:], parts[1]) ||
+		!decodeTraceHex(span.SpanID[:], parts[2]) || !decodeTraceHex(flags[:], parts[3]) {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+	span.Flags = flags[0]
+	if !span.IsValid() {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+	return span, nil
+}
+
+// IsValid reports whether the trace and span IDs are set.
+func (s SpanContext) IsValid() bool {
+	return s.TraceID != [16]byte{} && s.SpanID != [8]byte{}
+}
+
+// IsSampled reports whether the sampled flag is set.
+func (s SpanContext) IsSampled() bool {
+	return s.Flags&TRACE_FLAG_SAMPLED != 0
+}
+
+// Traceparent returns the traceparent header value of the span.
+func (s SpanContext) Traceparent() string {
+	return "00-" + hex.EncodeToString(s.TraceID[:]) + "-" + hex.EncodeToString(s.SpanID[:]) + "-" + hex.EncodeToString([]byte{s.Flags})
+}
+
+// Start implements Tracer.
+func (t W3CTracer) Start(ctx context.Context, req *http.Request) (context.Context, Span) {
+	parent := SpanContextFromContext(ctx)
+	span := &w3cSpan{
+		data: ClientSpan{Parent: parent, Method: req.Method, Host: req.URL.Host, Start: time.Now()},
+		end:  t.OnEnd,
+	}
+	span.data.SpanContext = parent
+	if !parent.IsValid() {
+		span.data.Parent = SpanContext{}
+		span.data.SpanContext = SpanContext{Flags: TRACE_FLAG_SAMPLED}
+		rand.Read(span.data.SpanContext.TraceID[:])
+	}
+	rand.Read(span.data.SpanContext.SpanID[:])
+	span.ctx = ContextWithSpanContext(ctx, span.data.SpanContext)
+	return span.ctx, span
+}
+
+// SpanContext implements Span.
+func (s *w3cSpan) SpanContext() SpanContext {
+	return s.data.SpanContext
+}
+
+// End implements Span.
+func (s *w3cSpan) End(statusCode int, err error) {
+	if s.end == nil {
+		return
+	}
+	s.data.StatusCode = statusCode
+	s.data.Err = err
+	s.data.Duration = time.Since(s.data.Start)
+	s.end(s.ctx, s.data)
+}
+
+// Close implements io.Closer.
+func (b *tracedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.onEnd)
+	return err
+}
+
+// decodeTraceHex decodes the lowercase hex string s into dst.
+func decodeTraceHex(dst []byte, s string) bool {
+	if len(s) != 2*len(dst) || strings.ToLower(s) != s {
+		return false
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err == nil
+}
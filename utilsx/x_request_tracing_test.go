@@ -0,0 +1,114 @@
+package utilsx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	span, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !span.IsSampled() || span.Traceparent() != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" {
+		t.Fatalf("unexpected span %+v", span)
+	}
+
+	// 未来版本允许附加字段
+	if _, err = ParseTraceparent("cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra"); err != nil {
+		t.Fatal(err)
+	}
+	for _, value := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01",
+	} {
+		if _, err = ParseTraceparent(value); err != ErrInvalidTraceparent {
+			t.Fatalf("expected invalid traceparent for %q", value)
+		}
+	}
+}
+
+func TestTracingMiddleware(t *testing.T) {
+	var headers []http.Header
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		headers = append(headers, r.Header.Clone())
+		first := len(headers) == 1
+		mu.Unlock()
+		if first {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	var spans []ClientSpan
+	tracer := W3CTracer{OnEnd: func(ctx context.Context, span ClientSpan) {
+		spans = append(spans, span)
+	}}
+	client := NewHttpClient(WithMiddleware(TracingMiddleware(tracer)))
+
+	// 上游的trace通过context传入，每次重试生成新的子span
+	incoming := http.Header{}
+	incoming.Set(TRACE_HEADER_PARENT, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	incoming.Set(TRACE_HEADER_STATE, "vendor=abc")
+	parent, err := ExtractSpanContext(incoming)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := ContextWithSpanContext(context.Background(), parent)
+	_, err = client.NewRequest(server.URL).SetRetryPolicy(&BackoffRetryPolicy{MaxAttempts: 2, RetryStatusCodes: []int{http.StatusServiceUnavailable}}).DoContext(ctx, HTTP_METHOD_GET)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	received := headers
+	mu.Unlock()
+	if len(spans) != 2 || len(received) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	for i, span := range spans {
+		if span.Parent != parent || span.SpanContext.TraceID != parent.TraceID || span.SpanContext.SpanID == parent.SpanID {
+			t.Fatalf("span %d is not a child of the incoming span: %+v", i, span)
+		}
+		if received[i].Get(TRACE_HEADER_PARENT) != span.SpanContext.Traceparent() || received[i].Get(TRACE_HEADER_STATE) != "vendor=abc" {
+			t.Fatalf("unexpected headers %v", received[i])
+		}
+		if span.Method != "GET" || span.Host != server.Listener.Addr().String() || span.Duration <= 0 {
+			t.Fatalf("unexpected span %+v", span)
+		}
+	}
+	if spans[0].StatusCode != http.StatusServiceUnavailable || spans[1].StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status codes %d %d", spans[0].StatusCode, spans[1].StatusCode)
+	}
+	if spans[0].SpanContext.SpanID == spans[1].SpanContext.SpanID {
+		t.Fatal("retries should have distinct spans")
+	}
+
+	// 没有上游trace时开启新的采样trace
+	spans = nil
+	if _, err = client.NewRequest(server.URL).Do(HTTP_METHOD_GET); err != nil {
+		t.Fatal(err)
+	}
+	if len(spans) != 1 || spans[0].Parent.IsValid() || !spans[0].SpanContext.IsValid() || !spans[0].SpanContext.IsSampled() {
+		t.Fatalf("expected a new root span, got %+v", spans)
+	}
+
+	// 传输错误也结束span
+	spans = nil
+	server.Close()
+	if _, err = client.NewRequest(server.URL).Do(HTTP_METHOD_GET); err == nil {
+		t.Fatal("expected transport error")
+	}
+	if len(spans) != 1 || spans[0].Err == nil || spans[0].StatusCode != 0 {
+		t.Fatalf("expected a failed span, got %+v", spans)
+	}
+}